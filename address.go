@@ -0,0 +1,42 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	addressDirection = `(?:N|S|E|W|NE|NW|SE|SW|North|South|East|West)\b\.?`
+	addressSuffix    = `(?:(?:Street|Avenue|Road|Boulevard|Drive|Lane|Way|Court|Circle|Place|Terrace|Parkway|Trail|Square|Loop)\b|(?:St|Ave|Rd|Blvd|Dr|Ln|Ct|Cir|Pl|Ter|Pkwy|Trl|Sq)\b\.?)`
+	addressWord      = `[A-Za-z0-9][A-Za-z0-9.'\-]*`
+	addressUnit      = `(?i:(?:Apt|Apartment|Unit|Suite|Ste|Floor|Fl|Room|Rm)\b\.?[ \t]*#?|#[ \t]*)[A-Za-z0-9][A-Za-z0-9\-]*`
+	addressSeparator = `(?:[ \t]*,[ \t]*(?:\r?\n[ \t]*)?|[ \t]*\r?\n[ \t]*|[ \t]+)`
+)
+
+var addressPatternRe = regexp.MustCompile(buildAddressPattern())
+
+// buildAddressPattern assembles a detector for postal address blocks: a
+// street line, highway or PO box, an optional unit, and an optional
+// "City, ST 12345" line, joined by commas, spaces or line breaks so the
+// whole block is redacted as a single span.
+func buildAddressPattern() string {
+	abbreviations := make([]string, 0, len(usGazetteer.states))
+	names := make([]string, 0, len(usGazetteer.states))
+	for _, state := range usGazetteer.states {
+		abbreviations = append(abbreviations, state.abbreviation)
+		names = append(names, regexp.QuoteMeta(state.name))
+	}
+
+	street := `\b\d{1,6}[A-Za-z]?[ \t]+(?:` + addressDirection + `[ \t]+)?` +
+		addressWord + `(?:[ \t]+` + addressWord + `){0,4}?[ \t]+` + addressSuffix +
+		`(?:[ \t]+` + addressDirection + `)?`
+	highway := `\b\d{1,6}[ \t]+(?:(?:State|US|U\.S\.|County)[ \t]+)?(?:Highway|Hwy|Route|Rte)\.?[ \t]+\d+[A-Za-z]?\b`
+	poBox := `(?i:\b(?:P\.?[ \t]*O\.?|Post[ \t]+Office)[ \t]*Box)[ \t]+\d+\b`
+	city := `[A-Z][A-Za-z.'\-]*(?:[ \t]+[A-Z][A-Za-z.'\-]*){0,3}`
+	state := `(?:` + strings.Join(abbreviations, "|") + `|` + strings.Join(names, "|") + `)\b`
+	zip := `\d{5}(?:-\d{4})?\b`
+
+	return `(?:` + street + `|` + highway + `|` + poBox + `)` +
+		`(?:` + addressSeparator + addressUnit + `)?` +
+		`(?:` + addressSeparator + city + `,?[ \t]+` + state + `(?:[ \t]+` + zip + `)?)?`
+}
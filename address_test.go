@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAddressPatternMatchesFullBlocks(t *testing.T) {
+	cases := map[string]string{
+		"Mail it to 123 N Main St. Apt 4B, Springfield, IL 62704 please.":      "123 N Main St. Apt 4B, Springfield, IL 62704",
+		"We moved to 45 Oak Terrace, Unit 12 last year.":                       "45 Oak Terrace, Unit 12",
+		"Send to P.O. Box 123\nAustin, TX 78701-1234\nThanks":                  "P.O. Box 123\nAustin, TX 78701-1234",
+		"The farm at 9870 State Highway 9 flooded.":                            "9870 State Highway 9",
+		"Our house at 77 Maple Circle\nSuite 300\nBoise, Idaho is small.":      "77 Maple Circle\nSuite 300\nBoise, Idaho",
+		"Office: 1600 Pennsylvania Avenue NW, Washington, DC 20500 is famous.": "1600 Pennsylvania Avenue NW, Washington, DC 20500",
+		"She lives at 12 Elm Place.":                                           "12 Elm Place",
+	}
+	for content, want := range cases {
+		got := addressPatternRe.FindString(content)
+		if got != want {
+			t.Fatalf("address in %q:\n got %q\nwant %q", content, got, want)
+		}
+	}
+}
+
+func TestRedactContentAddressBlockIsOneSpan(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	patterns = append(patterns, buildLocationPatterns(100000, nil)...)
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	redacted, counts := redactContent("Reach me at 123 N Main St.\nApt 4B\nSpringfield, IL 62704 anytime.", patterns, cfg)
+	if redacted != "Reach me at [REDACTED] anytime." {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+	if counts["street_address"] != 1 || len(counts) != 1 {
+		t.Fatalf("expected a single street_address redaction, got %#v", counts)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// outputModes are the permissions for files and directories a run creates.
+type outputModes struct {
+	file os.FileMode
+	dir  os.FileMode
+}
+
+// defaultOutputModes keep redacted essays and reports owner-only.
+var defaultOutputModes = outputModes{file: 0o600, dir: 0o700}
+
+// modeFlag parses an octal permission such as 0640 or 640.
+type modeFlag struct {
+	mode *os.FileMode
+}
+
+func (m modeFlag) String() string {
+	if m.mode == nil {
+		return ""
+	}
+	return fmt.Sprintf("%04o", uint32(*m.mode))
+}
+
+func (m modeFlag) Set(value string) error {
+	parsed, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || parsed > 0o777 {
+		return fmt.Errorf("invalid mode %q (expected octal permissions such as 0600)", value)
+	}
+	*m.mode = os.FileMode(parsed)
+	return nil
+}
+
+// registerModeFlags adds -file-mode and -dir-mode to a flag set.
+func registerModeFlags(fs *flag.FlagSet) *outputModes {
+	modes := defaultOutputModes
+	fs.Var(modeFlag{&modes.file}, "file-mode", "Permissions for written files, in octal")
+	fs.Var(modeFlag{&modes.dir}, "dir-mode", "Permissions for created directories, in octal")
+	return &modes
+}
+
+// writeAtomic streams a file into a temporary sibling and renames it over
+// path once it is complete, so readers and crashes never see a partial file.
+// The file gets exactly perm, regardless of umask; missing parent
+// directories are created with dirMode.
+func writeAtomic(path string, perm, dirMode os.FileMode, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// writeFileAtomic is writeAtomic for data already in memory.
+func writeFileAtomic(path string, data []byte, modes outputModes) error {
+	return writeAtomic(path, modes.file, modes.dir, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// outputInsideInput reports whether output is the input directory or lies
+// beneath it, comparing both the given and the symlink-resolved paths. A
+// second run would otherwise pick up and re-redact its own outputs.
+func outputInsideInput(input, output string) bool {
+	nested := func(in, out string) bool { return in == out || withinDir(in, out) }
+	if nested(input, output) {
+		return true
+	}
+	resolvedIn, err := filepath.EvalSymlinks(input)
+	if err != nil {
+		return false
+	}
+	resolvedOut, err := filepath.EvalSymlinks(output)
+	return err == nil && nested(resolvedIn, resolvedOut)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomicReplacesWholeFileWithMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "essay.txt")
+	modes := outputModes{file: 0o640, dir: 0o750}
+	if err := writeFileAtomic(path, []byte("first"), modes); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("second"), modes); err != nil {
+		t.Fatalf("rewrite error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o640 {
+		t.Fatalf("expected mode 0640, got %v %v", info, err)
+	}
+	if dirInfo, _ := os.Stat(filepath.Dir(path)); dirInfo.Mode().Perm() != 0o750 {
+		t.Fatalf("expected dir mode 0750, got %v", dirInfo.Mode().Perm())
+	}
+
+	failed := errors.New("disk full")
+	err = writeAtomic(path, 0o600, 0o700, func(w io.Writer) error {
+		w.Write([]byte("par"))
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if string(data) != "second" || len(entries) != 1 {
+		t.Fatalf("expected the previous file untouched and no temp files, got %q and %d entries", data, len(entries))
+	}
+}
+
+func TestOutputInsideInput(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "essays")
+	mustMkdir(t, input)
+	mustMkdir(t, filepath.Join(root, "redacted"))
+	if err := os.Symlink(filepath.Join(input, "out"), filepath.Join(root, "link")); err != nil {
+		t.Fatalf("symlink error: %v", err)
+	}
+	mustMkdir(t, filepath.Join(input, "out"))
+	cases := map[string]bool{
+		input:                               true,
+		filepath.Join(input, "redacted"):    true,
+		filepath.Join(root, "redacted"):     false,
+		filepath.Join(root, "essays-clean"): false,
+		filepath.Join(root, "link"):         true,
+	}
+	for output, want := range cases {
+		if got := outputInsideInput(input, output); got != want {
+			t.Fatalf("outputInsideInput(%s) = %t, want %t", output, got, want)
+		}
+	}
+}
+
+func TestModeFlagParsesOctal(t *testing.T) {
+	var mode os.FileMode
+	flag := modeFlag{&mode}
+	if err := flag.Set("0640"); err != nil || mode != 0o640 || flag.String() != "0640" {
+		t.Fatalf("expected 0640, got %v %v", mode, err)
+	}
+	for _, bad := range []string{"rw-r-----", "0999", "1777"} {
+		if err := flag.Set(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,664 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	bundleMagic         = "groupscholar-bundle/v1\n"
+	bundleVersion       = 1
+	bundlePassphraseEnv = "GS_BUNDLE_PASSPHRASE"
+	bundleChunkSize     = 64 << 10
+	bundleIterations    = 600000
+
+	stanzaX25519     = "x25519"
+	stanzaPassphrase = "pbkdf2-sha256"
+)
+
+// A bundle is the magic line, a JSON header line, then a tar stream sealed
+// in AES-256-GCM chunks under a random file key. The header carries one
+// stanza per recipient, each wrapping the file key, and is bound to every
+// chunk as additional data so it cannot be edited.
+type bundleHeader struct {
+	Version    int            `json:"version"`
+	Recipients []bundleStanza `json:"recipients"`
+}
+
+type bundleStanza struct {
+	Type       string `json:"type"`
+	KeyID      string `json:"key_id,omitempty"`
+	Ephemeral  string `json:"ephemeral,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// bundleKeys are the credentials for sealing or opening a bundle. Sealing
+// uses recipients and the passphrase; opening uses identity or the
+// passphrase.
+type bundleKeys struct {
+	recipients []*ecdh.PublicKey
+	identity   *ecdh.PrivateKey
+	passphrase string
+}
+
+// loadBundlePassphrase reads the passphrase from a file, then
+// $GS_BUNDLE_PASSPHRASE. It is never taken from the command line.
+func loadBundlePassphrase(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return os.Getenv(bundlePassphraseEnv), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+	passphrase := strings.TrimRight(string(data), "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase file %s is empty", path)
+	}
+	return passphrase, nil
+}
+
+// loadRecipient reads an X25519 public key (PEM), as written by
+// keygen -type x25519.
+func loadRecipient(path string) (*ecdh.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*ecdh.PublicKey)
+	if !ok || key.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("%s is not an X25519 public key", path)
+	}
+	return key, nil
+}
+
+// loadIdentity reads an X25519 private key (PEM).
+func loadIdentity(path string) (*ecdh.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*ecdh.PrivateKey)
+	if !ok || key.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("%s is not an X25519 private key", path)
+	}
+	return key, nil
+}
+
+// writeBundle archives files, which must lie under root, into an encrypted
+// bundle at path. The bundle itself is always owner-only.
+func writeBundle(path, root string, files []string, keys bundleKeys) (int, error) {
+	return sealBundle(path, keys, func(archive *tar.Writer) (int, error) {
+		return archiveFiles(archive, root, files)
+	})
+}
+
+// bundledOutputs lists the redacted outputs of a run. Manifests and journals
+// never go into a bundle, and reports are added separately by bundledReports.
+// A redacted output that is also one of the reserved paths is refused rather
+// than silently bundled.
+func bundledOutputs(rep report, root string, reserved []string) ([]string, error) {
+	excluded := map[string]bool{}
+	for _, path := range reserved {
+		if path != "" {
+			excluded[absOrSelf(path)] = true
+		}
+	}
+	seen := map[string]bool{}
+	files := []string{}
+	for _, entry := range rep.Details {
+		if entry.Skipped || !withinDir(root, entry.Target) || seen[entry.Target] {
+			continue
+		}
+		if excluded[entry.Target] {
+			return nil, fmt.Errorf("%s is also a report or metadata file and would carry original text", entry.Target)
+		}
+		seen[entry.Target] = true
+		files = append(files, entry.Target)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// bundledReports returns the report paths that lie inside root; reports
+// written elsewhere stay where they are.
+func bundledReports(root string, paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		if path != "" && withinDir(root, absOrSelf(path)) {
+			files = append(files, absOrSelf(path))
+		}
+	}
+	return files
+}
+
+// writeBackup seals a single file's original content into a bundle, so it
+// can be restored with unpack.
+func writeBackup(path, name string, info fs.FileInfo, data []byte, keys bundleKeys) error {
+	_, err := sealBundle(path, keys, func(archive *tar.Writer) (int, error) {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return 0, err
+		}
+		hdr.Name, hdr.Size = name, int64(len(data))
+		hdr.Uname, hdr.Gname = "", ""
+		if err := archive.WriteHeader(hdr); err != nil {
+			return 0, err
+		}
+		_, err = archive.Write(data)
+		return 1, err
+	})
+	return err
+}
+
+// sealBundle wraps a fresh file key for every recipient and streams the tar
+// entries written by add through the chunk sealer.
+func sealBundle(path string, keys bundleKeys, add func(*tar.Writer) (int, error)) (int, error) {
+	if len(keys.recipients) == 0 && keys.passphrase == "" {
+		return 0, errors.New("a bundle needs at least one recipient or a passphrase")
+	}
+	fileKey := make([]byte, 32)
+	if _, err := rand.Read(fileKey); err != nil {
+		return 0, err
+	}
+	header := bundleHeader{Version: bundleVersion}
+	for _, recipient := range keys.recipients {
+		stanza, err := wrapForRecipient(fileKey, recipient)
+		if err != nil {
+			return 0, err
+		}
+		header.Recipients = append(header.Recipients, stanza)
+	}
+	if keys.passphrase != "" {
+		stanza, err := wrapForPassphrase(fileKey, keys.passphrase)
+		if err != nil {
+			return 0, err
+		}
+		header.Recipients = append(header.Recipients, stanza)
+	}
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = writeAtomic(path, 0o600, defaultOutputModes.dir, func(out io.Writer) error {
+		w := bufio.NewWriter(out)
+		w.WriteString(bundleMagic)
+		w.Write(headerLine)
+		w.WriteByte('\n')
+		sealer, err := newChunkWriter(w, fileKey, bundleAAD(headerLine))
+		if err != nil {
+			return err
+		}
+		archive := tar.NewWriter(sealer)
+		if count, err = add(archive); err != nil {
+			return err
+		}
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		if err := sealer.Close(); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+	return count, err
+}
+
+func archiveFiles(archive *tar.Writer, root string, files []string) (int, error) {
+	count := 0
+	for _, path := range files {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return count, err
+		}
+		if !info.Mode().IsRegular() {
+			return count, fmt.Errorf("%s is not a regular file", path)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			return count, fmt.Errorf("%s is outside %s", path, root)
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return count, err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		hdr.Uname, hdr.Gname = "", ""
+		if err := archive.WriteHeader(hdr); err != nil {
+			return count, err
+		}
+		if err := copyFileTo(archive, path); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// unpackBundle decrypts a bundle into dest, which must not already contain
+// the bundled files. Entries that would land outside dest are rejected.
+func unpackBundle(path, dest string, keys bundleKeys, modes outputModes) (int, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+	magic, err := r.ReadString('\n')
+	if err != nil || magic != bundleMagic {
+		return 0, fmt.Errorf("%s is not a redaction bundle", path)
+	}
+	headerLine, err := r.ReadBytes('\n')
+	if err != nil {
+		return 0, fmt.Errorf("%s: truncated header", path)
+	}
+	headerLine = bytes.TrimSuffix(headerLine, []byte("\n"))
+	var header bundleHeader
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		return 0, fmt.Errorf("%s: invalid header: %w", path, err)
+	}
+	if header.Version != bundleVersion {
+		return 0, fmt.Errorf("%s: unsupported bundle version %d", path, header.Version)
+	}
+	fileKey, err := unwrapFileKey(header, keys)
+	if err != nil {
+		return 0, err
+	}
+	opener, err := newChunkReader(r, fileKey, bundleAAD(headerLine))
+	if err != nil {
+		return 0, err
+	}
+
+	archive := tar.NewReader(opener)
+	count := 0
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return count, fmt.Errorf("bundle entry %q escapes the output directory", hdr.Name)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(hdr.Name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, modes.dir); err != nil {
+				return count, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), modes.dir); err != nil {
+				return count, err
+			}
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, modes.file)
+			if err != nil {
+				return count, err
+			}
+			_, err = io.Copy(file, archive)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+		default:
+			return count, fmt.Errorf("bundle entry %q has unsupported type %c", hdr.Name, hdr.Typeflag)
+		}
+	}
+	// Reading to the end checks the final chunk, so a truncated bundle is
+	// reported even when the cut falls after the last tar entry.
+	if _, err := io.Copy(io.Discard, opener); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+func bundleAAD(headerLine []byte) []byte {
+	sum := sha256.Sum256(append([]byte(bundleMagic), headerLine...))
+	return sum[:]
+}
+
+func wrapForRecipient(fileKey []byte, recipient *ecdh.PublicKey) (bundleStanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	kek, err := recipientKEK(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	wrapped, err := sealKey(kek, fileKey)
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	return bundleStanza{
+		Type:       stanzaX25519,
+		KeyID:      publicKeyID(recipient.Bytes()),
+		Ephemeral:  base64.StdEncoding.EncodeToString(ephemeral.PublicKey().Bytes()),
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+	}, nil
+}
+
+// recipientKEK derives the key-wrapping key from an X25519 shared secret,
+// bound to both public keys.
+func recipientKEK(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	return hkdf.Key(sha256.New, shared, salt, "groupscholar-bundle x25519", 32)
+}
+
+func wrapForPassphrase(fileKey []byte, passphrase string) (bundleStanza, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return bundleStanza{}, err
+	}
+	kek, err := pbkdf2.Key(sha256.New, passphrase, salt, bundleIterations, 32)
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	wrapped, err := sealKey(kek, fileKey)
+	if err != nil {
+		return bundleStanza{}, err
+	}
+	return bundleStanza{
+		Type:       stanzaPassphrase,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: bundleIterations,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+	}, nil
+}
+
+// unwrapFileKey tries every stanza the caller holds a key for.
+func unwrapFileKey(header bundleHeader, keys bundleKeys) ([]byte, error) {
+	for _, stanza := range header.Recipients {
+		wrapped, err := base64.StdEncoding.DecodeString(stanza.WrappedKey)
+		if err != nil {
+			continue
+		}
+		var kek []byte
+		switch {
+		case stanza.Type == stanzaX25519 && keys.identity != nil:
+			if stanza.KeyID != publicKeyID(keys.identity.PublicKey().Bytes()) {
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(stanza.Ephemeral)
+			if err != nil {
+				continue
+			}
+			ephemeral, err := ecdh.X25519().NewPublicKey(raw)
+			if err != nil {
+				continue
+			}
+			shared, err := keys.identity.ECDH(ephemeral)
+			if err != nil {
+				continue
+			}
+			if kek, err = recipientKEK(shared, raw, keys.identity.PublicKey().Bytes()); err != nil {
+				continue
+			}
+		case stanza.Type == stanzaPassphrase && keys.passphrase != "":
+			salt, err := base64.StdEncoding.DecodeString(stanza.Salt)
+			if err != nil || stanza.Iterations < 1 {
+				continue
+			}
+			if kek, err = pbkdf2.Key(sha256.New, keys.passphrase, salt, stanza.Iterations, 32); err != nil {
+				continue
+			}
+		default:
+			continue
+		}
+		if fileKey, err := openKey(kek, wrapped); err == nil {
+			return fileKey, nil
+		}
+	}
+	return nil, errors.New("none of the bundle recipients match the given identity or passphrase")
+}
+
+// Each wrapping key is used exactly once, so a zero nonce is safe.
+func sealKey(kek, fileKey []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil), nil
+}
+
+func openKey(kek, wrapped []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is a big-endian chunk counter with the last byte marking the
+// final chunk, so chunks cannot be reordered, dropped or truncated unnoticed.
+func chunkNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// chunkWriter seals its input in bundleChunkSize chunks, each written as a
+// final flag byte, a 4-byte length and the ciphertext.
+type chunkWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	aad     []byte
+	buf     []byte
+	counter uint64
+}
+
+func newChunkWriter(w io.Writer, key, aad []byte) (*chunkWriter, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkWriter{w: w, aead: aead, aad: aad}, nil
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := min(bundleChunkSize-len(c.buf), len(p))
+		c.buf = append(c.buf, p[:n]...)
+		p = p[n:]
+		// A full chunk is only flushed once more data arrives, so the
+		// final chunk is never empty unless the whole stream is.
+		if len(c.buf) == bundleChunkSize && len(p) > 0 {
+			if err := c.flush(false); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (c *chunkWriter) Close() error {
+	return c.flush(true)
+}
+
+func (c *chunkWriter) flush(final bool) error {
+	sealed := c.aead.Seal(nil, chunkNonce(c.counter, final), c.buf, c.aad)
+	prefix := make([]byte, 5)
+	if final {
+		prefix[0] = 1
+	}
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(sealed)))
+	if _, err := c.w.Write(prefix); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(sealed); err != nil {
+		return err
+	}
+	c.counter++
+	c.buf = c.buf[:0]
+	return nil
+}
+
+type chunkReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	aad     []byte
+	buf     []byte
+	counter uint64
+	done    bool
+}
+
+func newChunkReader(r io.Reader, key, aad []byte) (*chunkReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkReader{r: r, aead: aead, aad: aad}, nil
+}
+
+var errBundleTruncated = errors.New("bundle is truncated or corrupted")
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+func (c *chunkReader) next() error {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(c.r, prefix); err != nil {
+		return errBundleTruncated
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > bundleChunkSize+uint32(c.aead.Overhead()) {
+		return errBundleTruncated
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(c.r, sealed); err != nil {
+		return errBundleTruncated
+	}
+	final := prefix[0] == 1
+	plain, err := c.aead.Open(nil, chunkNonce(c.counter, final), sealed, c.aad)
+	if err != nil {
+		return errors.New("bundle failed authentication; it was modified or the header does not match")
+	}
+	if final {
+		var extra [1]byte
+		if n, _ := c.r.Read(extra[:]); n > 0 {
+			return errors.New("bundle has data after its final chunk")
+		}
+		c.done = true
+	}
+	c.counter++
+	c.buf = plain
+	return nil
+}
+
+// stagedLabel describes a path inside the staging directory by its place in
+// the bundle.
+func stagedLabel(bundlePath, staging, path string) string {
+	if path == "" || !withinDir(staging, absOrSelf(path)) {
+		return path
+	}
+	rel, _ := filepath.Rel(staging, absOrSelf(path))
+	return bundlePath + ":" + filepath.ToSlash(rel)
+}
+
+// runUnpack decrypts a bundle into a new directory.
+func runUnpack(args []string) error {
+	fs := flag.NewFlagSet("unpack", flag.ExitOnError)
+	bundlePath := fs.String("bundle", "", "Encrypted bundle to unpack")
+	outputPath := fs.String("output", "", "Directory to extract into (must not already contain the bundled files)")
+	identityPath := fs.String("identity", "", "X25519 private key (PEM) of a recipient")
+	passphraseFile := fs.String("passphrase-file", "", "File containing the bundle passphrase (default: $"+bundlePassphraseEnv+")")
+	modes := registerModeFlags(fs)
+	fs.Parse(args)
+
+	if strings.TrimSpace(*bundlePath) == "" || strings.TrimSpace(*outputPath) == "" {
+		return errors.New("unpack requires -bundle and -output")
+	}
+	var keys bundleKeys
+	var err error
+	if *identityPath != "" {
+		if keys.identity, err = loadIdentity(*identityPath); err != nil {
+			return err
+		}
+	}
+	if keys.passphrase, err = loadBundlePassphrase(*passphraseFile); err != nil {
+		return err
+	}
+	if keys.identity == nil && keys.passphrase == "" {
+		return errors.New("unpack requires -identity, -passphrase-file or $" + bundlePassphraseEnv)
+	}
+	count, err := unpackBundle(*bundlePath, *outputPath, keys, *modes)
+	if err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", *bundlePath, err)
+	}
+	fmt.Printf("Unpacked %d files from %s into %s\n", count, *bundlePath, *outputPath)
+	return nil
+}
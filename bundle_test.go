@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTripForRecipientAndPassphrase(t *testing.T) {
+	root := t.TempDir()
+	mustMkdir(t, filepath.Join(root, "nested"))
+	large := strings.Repeat("Email [REDACTED] and phone [REDACTED].\n", 5000)
+	mustWrite(t, filepath.Join(root, "a.txt"), "[REDACTED]")
+	mustWrite(t, filepath.Join(root, "nested", "large.txt"), large)
+	identity, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	stranger, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	bundlePath := filepath.Join(t.TempDir(), "batch.gsb")
+	files := []string{filepath.Join(root, "a.txt"), filepath.Join(root, "nested", "large.txt")}
+	count, err := writeBundle(bundlePath, root, files, bundleKeys{recipients: []*ecdh.PublicKey{identity.PublicKey()}, passphrase: "correct horse"})
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 bundled files, got %d %v", count, err)
+	}
+	sealed, _ := os.ReadFile(bundlePath)
+	if strings.Contains(string(sealed), "[REDACTED]") {
+		t.Fatalf("bundle contains plaintext")
+	}
+
+	for name, keys := range map[string]bundleKeys{"identity": {identity: identity}, "passphrase": {passphrase: "correct horse"}} {
+		dest := t.TempDir()
+		if _, err := unpackBundle(bundlePath, dest, keys, defaultOutputModes); err != nil {
+			t.Fatalf("unpack with %s: %v", name, err)
+		}
+		data, err := os.ReadFile(filepath.Join(dest, "nested", "large.txt"))
+		if err != nil || string(data) != large {
+			t.Fatalf("unpack with %s: large file did not round-trip (%v)", name, err)
+		}
+	}
+	if _, err := unpackBundle(bundlePath, t.TempDir(), bundleKeys{identity: stranger, passphrase: "wrong"}, defaultOutputModes); err == nil {
+		t.Fatalf("expected unrelated keys to be rejected")
+	}
+}
+
+func TestBundleRejectsTamperingAndTruncation(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "a.txt"), strings.Repeat("[REDACTED] ", 20000))
+	bundlePath := filepath.Join(t.TempDir(), "batch.gsb")
+	keys := bundleKeys{passphrase: "correct horse"}
+	if _, err := writeBundle(bundlePath, root, []string{filepath.Join(root, "a.txt")}, keys); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	sealed, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	flipped := append([]byte{}, sealed...)
+	flipped[len(flipped)-20] ^= 1
+	mustWrite(t, bundlePath, string(flipped))
+	if _, err := unpackBundle(bundlePath, t.TempDir(), keys, defaultOutputModes); err == nil {
+		t.Fatalf("expected a modified bundle to fail")
+	}
+
+	mustWrite(t, bundlePath, string(sealed[:len(sealed)-1]))
+	if _, err := unpackBundle(bundlePath, t.TempDir(), keys, defaultOutputModes); err == nil {
+		t.Fatalf("expected a truncated bundle to fail")
+	}
+	// The passphrase still unwraps the key, but the header no longer
+	// matches the one bound to every chunk.
+	header := strings.Replace(string(sealed), `"version":1,`, `"version":1 ,`, 1)
+	mustWrite(t, bundlePath, header)
+	if _, err := unpackBundle(bundlePath, t.TempDir(), keys, defaultOutputModes); err == nil {
+		t.Fatalf("expected an edited header to fail")
+	}
+}
+
+func TestBundledOutputsSkipMetadata(t *testing.T) {
+	root := t.TempDir()
+	rep := report{ByPattern: map[string]int{}}
+	rep.add(fileReport{Source: "/in/b.txt", Target: filepath.Join(root, "b.txt"), Total: 1})
+	rep.add(fileReport{Source: "/in/a.txt", Target: filepath.Join(root, "nested", "a.txt"), Total: 1})
+	rep.add(fileReport{Source: "/in/clean.txt", Target: filepath.Join(root, "clean.txt"), Skipped: true})
+	reserved := []string{filepath.Join(root, "redaction-report.json"), filepath.Join(root, "report.html"), filepath.Join(root, manifestName)}
+
+	files, err := bundledOutputs(rep, root, reserved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 || files[0] != filepath.Join(root, "b.txt") || files[1] != filepath.Join(root, "nested", "a.txt") {
+		t.Fatalf("expected only the redacted outputs, got %v", files)
+	}
+
+	rep.add(fileReport{Source: "/in/report.html", Target: filepath.Join(root, "report.html"), Total: 1})
+	if _, err := bundledOutputs(rep, root, reserved); err == nil {
+		t.Fatalf("expected an output that collides with a report to be refused")
+	}
+}
+
+func TestBundledReportVerifiesAfterUnpack(t *testing.T) {
+	root := t.TempDir()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("keygen error: %v", err)
+	}
+	output := filepath.Join(root, "a.txt")
+	reportPath := filepath.Join(root, "redaction-report.json")
+	mustWrite(t, output, "[REDACTED]")
+	mustWrite(t, reportPath, "{}")
+	mustWrite(t, filepath.Join(root, manifestName), "{}")
+	outside := filepath.Join(t.TempDir(), "report.csv")
+
+	files := append([]string{output}, bundledReports(root, []string{reportPath, outside, ""})...)
+	if len(files) != 2 || files[1] != reportPath {
+		t.Fatalf("expected only the report inside the output directory, got %v", files)
+	}
+	integrityPath, err := writeIntegrityManifest(root, reportPath, files, key, defaultOutputModes)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "batch.gsb")
+	keys := bundleKeys{passphrase: "correct horse"}
+	if _, err := writeBundle(bundlePath, root, append(files, integrityPath), keys); err != nil {
+		t.Fatalf("bundle error: %v", err)
+	}
+
+	dest := t.TempDir()
+	if _, err := unpackBundle(bundlePath, dest, keys, defaultOutputModes); err != nil {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, manifestName)); err == nil {
+		t.Fatalf("expected run metadata to stay out of the bundle")
+	}
+	problems, err := verifyIntegrity(dest, filepath.Join(dest, integrityName), "", key.Public().(ed25519.PublicKey))
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected the unpacked bundle to verify, got %v %v", problems, err)
+	}
+}
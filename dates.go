@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const monthNamePattern = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+
+var monthNames = []string{
+	"january", "february", "march", "april", "may", "june",
+	"july", "august", "september", "october", "november", "december",
+}
+
+// dateLayoutSources describe the written and ISO date shapes detected by the
+// date pattern. Each source names its month, day, suffix and year groups so a
+// shifted date can be rendered back in the same shape. A dotted date with a
+// two-digit year must spell out day and month in full, so version numbers
+// such as 3.10.12 are not mistaken for dates.
+var dateLayoutSources = []string{
+	`(?i)(?P<month>` + monthNamePattern + `)\.?\s+(?P<day>\d{1,2})(?P<suffix>st|nd|rd|th)?,?\s+(?P<year>\d{4})`,
+	`(?i)(?P<day>\d{1,2})(?P<suffix>st|nd|rd|th)?\s+(?:of\s+)?(?P<month>` + monthNamePattern + `)\.?,?\s+(?P<year>\d{4})`,
+	`(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})`,
+	`(?P<day>\d{1,2})\.(?P<month>\d{1,2})\.(?P<year>\d{4})`,
+	`(?P<day>\d{2})\.(?P<month>\d{2})\.(?P<year>\d{2})`,
+}
+
+// numericDateSource matches the US month-first shape caught by the dob pattern.
+const numericDateSource = `(?P<month>\d{1,2})[/-](?P<day>\d{1,2})[/-](?P<year>\d{4})`
+
+var (
+	dateLayouts   = compileDateLayouts(append(append([]string{}, dateLayoutSources...), numericDateSource))
+	datePatternRe = compileDatePattern(dateLayoutSources)
+)
+
+func compileDateLayouts(sources []string) []*regexp.Regexp {
+	layouts := make([]*regexp.Regexp, 0, len(sources))
+	for _, src := range sources {
+		prefix := ""
+		if strings.HasPrefix(src, "(?i)") {
+			prefix = "(?i)"
+			src = strings.TrimPrefix(src, "(?i)")
+		}
+		layouts = append(layouts, regexp.MustCompile(prefix+`^`+src+`$`))
+	}
+	return layouts
+}
+
+func compileDatePattern(sources []string) *regexp.Regexp {
+	groupName := regexp.MustCompile(`\(\?P<[a-z]+>`)
+	parts := make([]string, 0, len(sources))
+	for _, src := range sources {
+		src = groupName.ReplaceAllString(src, "(?:")
+		if strings.HasPrefix(src, "(?i)") {
+			src = "(?i:" + strings.TrimPrefix(src, "(?i)") + ")"
+		}
+		parts = append(parts, src)
+	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(parts, "|") + `)\b`)
+}
+
+type parsedDate struct {
+	value  time.Time
+	raw    string
+	layout *regexp.Regexp
+	groups []int
+}
+
+func parseDate(raw string) (parsedDate, bool) {
+	for _, layout := range dateLayouts {
+		groups := layout.FindStringSubmatchIndex(raw)
+		if groups == nil {
+			continue
+		}
+		parsed := parsedDate{raw: raw, layout: layout, groups: groups}
+		month, ok := parseMonth(parsed.group("month"))
+		if !ok {
+			continue
+		}
+		day, err := strconv.Atoi(parsed.group("day"))
+		if err != nil {
+			continue
+		}
+		year, err := strconv.Atoi(parsed.group("year"))
+		if err != nil {
+			continue
+		}
+		if len(parsed.group("year")) == 2 {
+			year = expandTwoDigitYear(year)
+		}
+		value := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if value.Day() != day || int(value.Month()) != month {
+			continue
+		}
+		parsed.value = value
+		return parsed, true
+	}
+	return parsedDate{}, false
+}
+
+func (p parsedDate) group(name string) string {
+	idx := p.layout.SubexpIndex(name)
+	if idx < 0 || p.groups[2*idx] < 0 {
+		return ""
+	}
+	return p.raw[p.groups[2*idx]:p.groups[2*idx+1]]
+}
+
+// render rewrites the matched date as value, keeping the original month
+// spelling, casing, zero padding, ordinal suffix and year width.
+func (p parsedDate) render(value time.Time) string {
+	type span struct {
+		start, end int
+		text       string
+	}
+	_, err := strconv.Atoi(p.group("month"))
+	numeric := err == nil
+	var spans []span
+	for _, name := range []string{"month", "day", "suffix", "year"} {
+		idx := p.layout.SubexpIndex(name)
+		if idx < 0 || p.groups[2*idx] < 0 {
+			continue
+		}
+		original := p.raw[p.groups[2*idx]:p.groups[2*idx+1]]
+		var text string
+		switch name {
+		case "month":
+			text = formatMonth(original, int(value.Month()), numeric)
+		case "day":
+			text = formatNumber(original, value.Day(), numeric)
+		case "suffix":
+			text = matchCase(original, ordinalSuffix(value.Day()))
+		case "year":
+			if len(original) == 2 {
+				text = fmt.Sprintf("%02d", value.Year()%100)
+			} else {
+				text = fmt.Sprintf("%04d", value.Year())
+			}
+		}
+		spans = append(spans, span{start: p.groups[2*idx], end: p.groups[2*idx+1], text: text})
+	}
+
+	var b strings.Builder
+	last := 0
+	for len(spans) > 0 {
+		next := 0
+		for i := range spans {
+			if spans[i].start < spans[next].start {
+				next = i
+			}
+		}
+		s := spans[next]
+		b.WriteString(p.raw[last:s.start])
+		b.WriteString(s.text)
+		last = s.end
+		spans = append(spans[:next], spans[next+1:]...)
+	}
+	b.WriteString(p.raw[last:])
+	return b.String()
+}
+
+func parseMonth(raw string) (int, bool) {
+	if raw == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(raw); err == nil {
+		return n, n >= 1 && n <= 12
+	}
+	lower := strings.ToLower(raw)
+	for i, name := range monthNames {
+		if strings.HasPrefix(name, lower) && len(lower) >= 3 {
+			return i + 1, true
+		}
+	}
+	if lower == "sept" {
+		return 9, true
+	}
+	return 0, false
+}
+
+func formatMonth(original string, month int, numeric bool) string {
+	if numeric {
+		return formatNumber(original, month, numeric)
+	}
+	name := monthNames[month-1]
+	if len(original) <= 4 && strings.ToLower(original) != monthNames[month-1] {
+		if len(name) > 3 {
+			name = name[:3]
+		}
+	}
+	return matchCase(original, name)
+}
+
+// formatNumber keeps two-digit padding for numeric layouts and for written
+// days that were zero padded ("March 03").
+func formatNumber(original string, value int, numeric bool) string {
+	if len(original) == 2 && (numeric || original[0] == '0') {
+		return fmt.Sprintf("%02d", value)
+	}
+	return strconv.Itoa(value)
+}
+
+func matchCase(original, value string) string {
+	switch {
+	case original == strings.ToUpper(original) && original != strings.ToLower(original):
+		return strings.ToUpper(value)
+	case original == strings.ToLower(original):
+		return strings.ToLower(value)
+	default:
+		return strings.ToUpper(value[:1]) + strings.ToLower(value[1:])
+	}
+}
+
+func ordinalSuffix(day int) string {
+	if day%100 >= 11 && day%100 <= 13 {
+		return "th"
+	}
+	switch day % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	default:
+		return "th"
+	}
+}
+
+func expandTwoDigitYear(year int) int {
+	if year < 70 {
+		return 2000 + year
+	}
+	return 1900 + year
+}
+
+func validDate(match string) bool {
+	_, ok := parseDate(match)
+	return ok
+}
+
+// outsideDottedRun rejects a date that is only part of a longer dotted run
+// of numbers, such as an IPv4 address (10.11.12.13) or a four-part version.
+func outsideDottedRun(content string, start, end int) bool {
+	if start >= 2 && content[start-1] == '.' && isASCIIDigit(content[start-2]) {
+		return false
+	}
+	if end+1 < len(content) && content[end] == '.' && isASCIIDigit(content[end+1]) {
+		return false
+	}
+	return true
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isDateLabel(label string) bool {
+	return label == "dob" || label == "date"
+}
+
+func shiftDate(match string, days int) (string, bool) {
+	parsed, ok := parseDate(match)
+	if !ok {
+		return "", false
+	}
+	return parsed.render(parsed.value.AddDate(0, 0, days)), true
+}
+
+// dateShiftOffset derives a non-zero offset in [-maxDays, maxDays] for one
+// applicant so every date in their file moves by the same amount.
+func dateShiftOffset(key, applicant string, maxDays int) int {
+	if maxDays <= 0 {
+		return 0
+	}
+	sum := hashMatch(applicant, key, 16)
+	raw, err := hex.DecodeString(sum)
+	if err != nil {
+		return maxDays
+	}
+	n := binary.BigEndian.Uint64(raw)
+	offset := int(n%uint64(maxDays)) + 1
+	if n&(1<<63) != 0 {
+		offset = -offset
+	}
+	return offset
+}
+
+func randomDateShiftKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDatePatternMatchesWrittenAndISODates(t *testing.T) {
+	content := "Born on March 3rd, 2007, moved 3 March 2008, started 2009-03-03 and left 03.03.07."
+	matches := datePatternRe.FindAllString(content, -1)
+	want := []string{"March 3rd, 2007", "3 March 2008", "2009-03-03", "03.03.07"}
+	if len(matches) != len(want) {
+		t.Fatalf("unexpected matches: %#v", matches)
+	}
+	for i, match := range matches {
+		if match != want[i] {
+			t.Fatalf("match %d: got %q want %q", i, match, want[i])
+		}
+		if !validDate(match) {
+			t.Fatalf("expected %q to parse", match)
+		}
+	}
+	if validDate("2009-02-30") {
+		t.Fatalf("expected impossible date to be rejected")
+	}
+}
+
+func TestShiftDatePreservesFormat(t *testing.T) {
+	cases := map[string]string{
+		"March 3rd, 2007": "March 13th, 2007",
+		"Mar. 30, 2007":   "Apr. 9, 2007",
+		"3 MARCH 2007":    "13 MARCH 2007",
+		"2007-03-03":      "2007-03-13",
+		"03.03.07":        "13.03.07",
+		"3/3/2007":        "3/13/2007",
+	}
+	for input, want := range cases {
+		got, ok := shiftDate(input, 10)
+		if !ok {
+			t.Fatalf("expected %q to shift", input)
+		}
+		if got != want {
+			t.Fatalf("shift %q: got %q want %q", input, got, want)
+		}
+	}
+}
+
+func TestRedactContentDateShiftKeepsDurations(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "salt", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = enableDateShift(cfg, 30)
+	if err != nil {
+		t.Fatalf("date shift error: %v", err)
+	}
+	cfg.shiftDays = dateShiftOffset(cfg.shiftKey, "applicant.txt", cfg.shiftMax)
+	if cfg.shiftDays == 0 || cfg.shiftDays < -30 || cfg.shiftDays > 30 {
+		t.Fatalf("unexpected shift offset: %d", cfg.shiftDays)
+	}
+
+	redacted, counts := redactContent("Started 2020-01-01, finished 2020-01-11.", patterns, cfg)
+	if counts["date"] != 2 {
+		t.Fatalf("expected 2 date redactions, got %d", counts["date"])
+	}
+	if strings.Contains(redacted, "2020-01-01") || strings.Contains(redacted, "[REDACTED]") {
+		t.Fatalf("expected shifted dates, got %q", redacted)
+	}
+	dates := datePatternRe.FindAllString(redacted, -1)
+	if len(dates) != 2 {
+		t.Fatalf("expected two shifted dates in %q", redacted)
+	}
+	first, _ := parseDate(dates[0])
+	second, _ := parseDate(dates[1])
+	if days := second.value.Sub(first.value).Hours() / 24; days != 10 {
+		t.Fatalf("expected 10 days between shifted dates, got %v", days)
+	}
+}
+
+func TestDatePatternSkipsAddressesAndVersions(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "salt", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	content := "Server 10.1.2.30 and 10.11.12.13 ran python 3.10.12, build 1.10.11.2024; left 03.03.07."
+	for _, set := range [][]pattern{patterns, keepPrivateAddresses(patterns)} {
+		_, counts := redactContent(content, set, cfg)
+		if counts["date"] != 1 {
+			t.Fatalf("expected only the real date to be redacted, got %#v", counts)
+		}
+	}
+	redacted, _ := redactContent(content, keepPrivateAddresses(patterns), cfg)
+	for _, kept := range []string{"10.1.2.30", "10.11.12.13", "python 3.10.12", "1.10.11.2024"} {
+		if !strings.Contains(redacted, kept) {
+			t.Fatalf("expected %q to survive, got %q", kept, redacted)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import "unicode/utf8"
+
+// Report detail levels for -report-detail.
+const (
+	detailCounts  = "counts"
+	detailMatches = "matches"
+)
+
+// matchDetail is the per-match audit record included with -report-detail
+// matches. The original value is only present as a keyed hash.
+type matchDetail struct {
+	Label       string `json:"label"`
+	Offset      int    `json:"offset"`
+	Length      int    `json:"length"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	Replacement string `json:"replacement"`
+	Validator   string `json:"validator"`
+	ValueHash   string `json:"value_hash"`
+}
+
+// finding is the location of one redaction in a source file and of its
+// replacement in the output, shared by the JSON, SARIF and HTML reports and
+// the manifest. It never holds the matched text, only its keyed hash, and not
+// even that without a hash key: an unkeyed hash of a short value such as an
+// SSN can be brute-forced. Lines and columns are 1-based and count Unicode
+// code points; offsets count bytes.
+type finding struct {
+	Label        string `json:"label"`
+	Offset       int    `json:"offset"`
+	Length       int    `json:"length"`
+	Line         int    `json:"line"`
+	Column       int    `json:"column"`
+	EndLine      int    `json:"end_line"`
+	EndColumn    int    `json:"end_column"`
+	OutputOffset int    `json:"output_offset"`
+	OutputLength int    `json:"output_length"`
+	Replacement  string `json:"replacement"`
+	Validated    bool   `json:"validated"`
+	ValueHash    string `json:"value_hash,omitempty"`
+}
+
+// locateFindings converts byte-offset matches into line/column findings,
+// hashing each matched value when a hash key is set. Matches must be sorted by
+// start offset, as findMatches returns them.
+func locateFindings(content string, matches []match, key string) []finding {
+	findings := make([]finding, 0, len(matches))
+	line, column, pos, shift := 1, 1, 0, 0
+	advance := func(to int) {
+		for pos < to {
+			r, size := utf8.DecodeRuneInString(content[pos:])
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+			pos += size
+		}
+	}
+	for _, m := range matches {
+		advance(m.start)
+		f := finding{Label: m.label, Offset: m.start, Length: m.end - m.start, Line: line, Column: column}
+		advance(m.end)
+		f.EndLine, f.EndColumn = line, column
+		f.OutputOffset, f.OutputLength = m.start+shift, len(m.replacement)
+		f.Replacement, f.Validated = m.replacement, m.validated
+		if key != "" {
+			f.ValueHash = hashMatch(content[m.start:m.end], key, 64)
+		}
+		shift += len(m.replacement) - f.Length
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// validatorOutcome describes the validator result for a redacted match.
+// Candidates that fail validation are never redacted, so a recorded match
+// either passed its validator or its pattern has none.
+func validatorOutcome(validated bool) string {
+	if validated {
+		return "passed"
+	}
+	return "none"
+}
+
+func (rep *report) attachMatchDetails() {
+	for i := range rep.Details {
+		entry := &rep.Details[i]
+		entry.Matches = make([]matchDetail, 0, len(entry.Findings))
+		for _, f := range entry.Findings {
+			entry.Matches = append(entry.Matches, matchDetail{
+				Label:       f.Label,
+				Offset:      f.Offset,
+				Length:      f.Length,
+				Line:        f.Line,
+				Column:      f.Column,
+				Replacement: f.Replacement,
+				Validator:   validatorOutcome(f.Validated),
+				ValueHash:   f.ValueHash,
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttachMatchDetails(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "essay.txt")
+	mustWrite(t, input, "Card 4111 1111 1111 1111\nEmail jane@example.com")
+
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "[{label}:{n}]", false, "key", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	entry, _, err := redactFile(input, root, "", patterns, cfg, true, false, defaultOutputModes)
+	if err != nil {
+		t.Fatalf("redactFile error: %v", err)
+	}
+	rep := report{SchemaVersion: reportSchemaVersion, ByPattern: map[string]int{}}
+	rep.add(entry)
+
+	plain, err := json.Marshal(rep)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if strings.Contains(string(plain), `"matches"`) {
+		t.Fatalf("expected no match records at the default detail level")
+	}
+
+	rep.attachMatchDetails()
+	matches := rep.Details[0].Matches
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 match records, got %+v", matches)
+	}
+	card, email := matches[0], matches[1]
+	if card.Label != "credit_card" || card.Validator != "passed" || card.Offset != 5 || card.Length != 19 || card.Replacement != "[credit_card:1]" {
+		t.Fatalf("unexpected card record: %+v", card)
+	}
+	if email.Label != "email" || email.Validator != "none" || email.Line != 2 || email.Column != 7 {
+		t.Fatalf("unexpected email record: %+v", email)
+	}
+	if email.ValueHash != hashMatch("jane@example.com", "key", 64) {
+		t.Fatalf("expected keyed value hash, got %q", email.ValueHash)
+	}
+	detailed, err := json.Marshal(rep)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if strings.Contains(string(detailed), "jane@example.com") || strings.Contains(string(detailed), "4111") {
+		t.Fatalf("match records must not contain original values: %s", detailed)
+	}
+}
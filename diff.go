@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	diffSame    = ' '
+	diffRemoved = '-'
+	diffAdded   = '+'
+)
+
+// diffLine is one line of a hunk. Context lines carry both line numbers;
+// removed lines only the original one and added lines only the redacted one.
+type diffLine struct {
+	Kind     byte
+	Text     string
+	OrigLine int
+	OutLine  int
+}
+
+type diffHunk struct {
+	OrigStart, OrigCount int
+	OutStart, OutCount   int
+	Lines                []diffLine
+}
+
+// diffHunks builds line hunks between a source and its redacted output.
+// Redaction only rewrites the spans recorded in findings, so the changed
+// lines come straight from those spans instead of a general-purpose diff,
+// and every other line maps one-to-one between the two texts.
+func diffHunks(original, redacted string, findings []finding, context int) []diffHunk {
+	origLines, outLines := splitLines(original), splitLines(redacted)
+	origStarts, outStarts := lineStarts(original), lineStarts(redacted)
+
+	type region struct{ a1, a2, b1, b2 int }
+	var regions []region
+	for _, f := range findings {
+		r := region{
+			a1: lineAt(origStarts, f.Offset),
+			a2: lineAt(origStarts, f.Offset+max(f.Length-1, 0)),
+			b1: lineAt(outStarts, f.OutputOffset),
+			b2: lineAt(outStarts, f.OutputOffset+max(f.OutputLength-1, 0)),
+		}
+		if n := len(regions); n > 0 && r.a1 <= regions[n-1].a2+1 {
+			regions[n-1].a2 = max(regions[n-1].a2, r.a2)
+			regions[n-1].b2 = max(regions[n-1].b2, r.b2)
+			continue
+		}
+		regions = append(regions, r)
+	}
+
+	var hunks []diffHunk
+	for i := 0; i < len(regions); {
+		j := i
+		for j+1 < len(regions) && regions[j+1].a1-regions[j].a2-1 <= 2*context {
+			j++
+		}
+		first, last := regions[i], regions[j]
+		lead := min(context, first.a1)
+		trail := min(context, len(origLines)-1-last.a2)
+		h := diffHunk{OrigStart: first.a1 - lead + 1, OutStart: first.b1 - lead + 1}
+		addContext := func(a, b, n int) {
+			for k := 0; k < n; k++ {
+				h.Lines = append(h.Lines, diffLine{Kind: diffSame, Text: origLines[a+k], OrigLine: a + k + 1, OutLine: b + k + 1})
+			}
+		}
+		addContext(first.a1-lead, first.b1-lead, lead)
+		for k := i; k <= j; k++ {
+			r := regions[k]
+			if k > i {
+				prev := regions[k-1]
+				addContext(prev.a2+1, prev.b2+1, r.a1-prev.a2-1)
+			}
+			for a := r.a1; a <= r.a2; a++ {
+				h.Lines = append(h.Lines, diffLine{Kind: diffRemoved, Text: origLines[a], OrigLine: a + 1})
+			}
+			for b := r.b1; b <= r.b2; b++ {
+				h.Lines = append(h.Lines, diffLine{Kind: diffAdded, Text: outLines[b], OutLine: b + 1})
+			}
+		}
+		addContext(last.a2+1, last.b2+1, trail)
+		for _, line := range h.Lines {
+			if line.Kind != diffAdded {
+				h.OrigCount++
+			}
+			if line.Kind != diffRemoved {
+				h.OutCount++
+			}
+		}
+		hunks = append(hunks, h)
+		i = j + 1
+	}
+	return hunks
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' && i+1 < len(text) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineAt returns the 0-based line containing the byte offset.
+func lineAt(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+}
+
+type diffStyle struct {
+	sideBySide bool
+	width      int
+	color      bool
+}
+
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+func (s diffStyle) paint(code, text string) string {
+	if !s.color {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// writeUnifiedDiff prints hunks in unified diff format.
+func writeUnifiedDiff(w io.Writer, name string, hunks []diffHunk, style diffStyle) {
+	fmt.Fprintf(w, "--- a/%s\n+++ b/%s\n", name, name)
+	for _, h := range hunks {
+		fmt.Fprintln(w, style.paint(ansiCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OrigStart, h.OrigCount, h.OutStart, h.OutCount)))
+		for _, line := range h.Lines {
+			switch line.Kind {
+			case diffRemoved:
+				fmt.Fprintln(w, style.paint(ansiRed, "-"+line.Text))
+			case diffAdded:
+				fmt.Fprintln(w, style.paint(ansiGreen, "+"+line.Text))
+			default:
+				fmt.Fprintln(w, " "+line.Text)
+			}
+		}
+	}
+}
+
+// diffRow is a side-by-side row: a removed line paired with the added line
+// that replaced it, or the same context line on both sides.
+type diffRow struct {
+	Kind      byte
+	Left      string
+	Right     string
+	LeftLine  int
+	RightLine int
+}
+
+// Changed reports whether the row differs between the two sides.
+func (r diffRow) Changed() bool {
+	return r.Kind != diffSame
+}
+
+func sideBySideRows(h diffHunk) []diffRow {
+	var rows []diffRow
+	var removed, added []diffLine
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			row := diffRow{Kind: diffRemoved}
+			if i < len(removed) {
+				row.Left, row.LeftLine = removed[i].Text, removed[i].OrigLine
+			}
+			if i < len(added) {
+				row.Right, row.RightLine = added[i].Text, added[i].OutLine
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+	for _, line := range h.Lines {
+		switch line.Kind {
+		case diffRemoved:
+			removed = append(removed, line)
+		case diffAdded:
+			added = append(added, line)
+		default:
+			flush()
+			rows = append(rows, diffRow{Kind: diffSame, Left: line.Text, Right: line.Text, LeftLine: line.OrigLine, RightLine: line.OutLine})
+		}
+	}
+	flush()
+	return rows
+}
+
+// writeSideBySideDiff prints each hunk as two columns truncated to width.
+func writeSideBySideDiff(w io.Writer, name string, hunks []diffHunk, style diffStyle) {
+	fmt.Fprintf(w, "=== %s\n", name)
+	for _, h := range hunks {
+		fmt.Fprintln(w, style.paint(ansiCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OrigStart, h.OrigCount, h.OutStart, h.OutCount)))
+		for _, row := range sideBySideRows(h) {
+			left := padColumn(row.Left, style.width)
+			marker := "   "
+			if row.Kind == diffRemoved {
+				left = style.paint(ansiRed, left)
+				marker = " | "
+			}
+			right := truncateColumn(row.Right, style.width)
+			if row.Kind == diffRemoved {
+				right = style.paint(ansiGreen, right)
+			}
+			fmt.Fprintln(w, left+marker+right)
+		}
+	}
+}
+
+func truncateColumn(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
+
+func padColumn(text string, width int) string {
+	text = truncateColumn(text, width)
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+}
+
+// runDiff redacts in memory with the usual pattern and mask flags and prints
+// what would change. Nothing is written to disk, so originals only ever
+// appear in the terminal (or the optional local HTML file).
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	inputPath := fs.String("input", "", "File or directory to compare")
+	extensions := fs.String("extensions", ".txt,.md,.csv", "Comma-separated list of file extensions to include for directories")
+	format := fs.String("format", "unified", "Diff format: unified or side-by-side")
+	contextLines := fs.Int("context", 3, "Unchanged lines to show around each change")
+	width := fs.Int("width", 60, "Column width for -format side-by-side")
+	color := fs.Bool("color", false, "Color removed and added lines")
+	htmlPath := fs.String("html", "", "Optional path for an HTML report with a side-by-side diff per file")
+	patternOpts := registerPatternFlags(fs)
+	maskOpts := registerMaskFlags(fs)
+	fs.Parse(args)
+
+	if strings.TrimSpace(*inputPath) == "" {
+		return errors.New("diff requires -input")
+	}
+	if *format != "unified" && *format != "side-by-side" {
+		return fmt.Errorf("unknown diff format %q (expected unified or side-by-side)", *format)
+	}
+	if *contextLines < 0 || *width < 10 {
+		return errors.New("-context must be >= 0 and -width >= 10")
+	}
+	patterns, err := patternOpts.build()
+	if err != nil {
+		return err
+	}
+	maskCfg, err := maskOpts.build()
+	if err != nil {
+		return err
+	}
+	absInput, err := filepath.Abs(*inputPath)
+	if err != nil {
+		return err
+	}
+	files, err := listFiles(absInput, parseExtensions(*extensions))
+	if err != nil {
+		return fmt.Errorf("failed to collect files: %w", err)
+	}
+
+	style := diffStyle{sideBySide: *format == "side-by-side", width: *width, color: *color}
+	rep := report{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   time.Now().Format(time.RFC3339),
+		InputPath:     absInput,
+		OutputPath:    "(diff)",
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+	}
+	previews := map[string]string{}
+	for _, path := range files {
+		entry, redacted, err := redactFile(path, absInput, "", patterns, maskCfg, true, false, defaultOutputModes)
+		if err != nil {
+			return fmt.Errorf("failed to redact %s: %w", path, err)
+		}
+		rep.add(entry)
+		previews[path] = redacted
+		if entry.Total == 0 {
+			continue
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hunks := diffHunks(string(original), redacted, entry.Findings, *contextLines)
+		name := displayPath(absInput, path)
+		if style.sideBySide {
+			writeSideBySideDiff(os.Stdout, name, hunks, style)
+		} else {
+			writeUnifiedDiff(os.Stdout, name, hunks, style)
+		}
+	}
+	rep.sortDetails()
+	if *htmlPath != "" {
+		return writeHTMLReport(*htmlPath, rep, previews, *contextLines, defaultOutputModes)
+	}
+	return nil
+}
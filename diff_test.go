@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func diffFor(t *testing.T, original string) (string, []finding) {
+	t.Helper()
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	matches := findMatches(original, patterns, cfg)
+	return applyMatches(original, matches), locateFindings(original, matches, "")
+}
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	original := "Intro\nEmail jane@example.com\nPhone 555-123-4567\none\ntwo\nthree\nfour\nfive\nsix\nLast a@b.org\n"
+	redacted, findings := diffFor(t, original)
+
+	var b strings.Builder
+	writeUnifiedDiff(&b, "essay.txt", diffHunks(original, redacted, findings, 1), diffStyle{})
+	want := "--- a/essay.txt\n+++ b/essay.txt\n" +
+		"@@ -1,4 +1,4 @@\n Intro\n-Email jane@example.com\n-Phone 555-123-4567\n+Email [REDACTED]\n+Phone [REDACTED]\n one\n" +
+		"@@ -9,2 +9,2 @@\n six\n-Last a@b.org\n+Last [REDACTED]\n"
+	if b.String() != want {
+		t.Fatalf("unexpected diff:\n%s", b.String())
+	}
+}
+
+func TestDiffHunksCollapsedLines(t *testing.T) {
+	original := "Send to 123 N Main St.\nApt 4B\nSpringfield, IL 62704\nThanks\n"
+	redacted, findings := diffFor(t, original)
+	hunks := diffHunks(original, redacted, findings, 3)
+	if len(hunks) != 1 {
+		t.Fatalf("expected one hunk, got %+v", hunks)
+	}
+	h := hunks[0]
+	if h.OrigCount != 4 || h.OutCount != 2 {
+		t.Fatalf("expected 4 original lines to become 2, got %+v", h)
+	}
+	rows := sideBySideRows(h)
+	if len(rows) != 4 || !rows[0].Changed() || rows[0].Right != "Send to [REDACTED]" || rows[1].Right != "" || rows[3].Changed() {
+		t.Fatalf("unexpected side-by-side rows: %+v", rows)
+	}
+	if rows[3].LeftLine != 4 || rows[3].RightLine != 2 {
+		t.Fatalf("expected context line numbers 4 and 2, got %+v", rows[3])
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// surrogateStream yields pseudo-random values for one match. With a key it is
+// an HMAC-SHA256 counter stream, so the same value always maps to the same
+// surrogate; without a key it reads from crypto/rand.
+type surrogateStream struct {
+	key     []byte
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func newSurrogateStream(key, label, value string) *surrogateStream {
+	s := &surrogateStream{}
+	if key != "" {
+		s.key = []byte(key)
+		s.seed = []byte(label + "\x00" + value)
+	}
+	return s
+}
+
+func (s *surrogateStream) next(n int) int {
+	if len(s.buf) < 4 {
+		if s.key == nil {
+			s.buf = make([]byte, 32)
+			if _, err := rand.Read(s.buf); err != nil {
+				panic("crypto/rand failed: " + err.Error())
+			}
+		} else {
+			mac := hmac.New(sha256.New, s.key)
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], s.counter)
+			s.counter++
+			mac.Write(counter[:])
+			mac.Write(s.seed)
+			s.buf = mac.Sum(nil)
+		}
+	}
+	v := binary.BigEndian.Uint32(s.buf[:4])
+	s.buf = s.buf[4:]
+	return int(v % uint32(n))
+}
+
+// formatPreservingSurrogate replaces digits with digits and ASCII letters
+// with letters of the same case, keeping separators and length. Credit card
+// surrogates keep a valid Luhn check digit and SSN surrogates use the
+// never-issued 9xx area with group 00.
+func formatPreservingSurrogate(label, value, key string) string {
+	stream := newSurrogateStream(key, label, value)
+	out := []rune(value)
+	var digits []int
+	for i, r := range out {
+		switch {
+		case r >= '0' && r <= '9':
+			out[i] = rune('0' + stream.next(10))
+			digits = append(digits, i)
+		case r >= 'a' && r <= 'z':
+			out[i] = rune('a' + stream.next(26))
+		case r >= 'A' && r <= 'Z':
+			out[i] = rune('A' + stream.next(26))
+		}
+	}
+
+	switch label {
+	case "credit_card":
+		if len(digits) > 1 {
+			fixLuhnCheckDigit(out, digits)
+		}
+	case "ssn":
+		if len(digits) == 9 {
+			out[digits[0]] = '9'
+			out[digits[3]] = '0'
+			out[digits[4]] = '0'
+		}
+	}
+	return string(out)
+}
+
+// fixLuhnCheckDigit rewrites the last digit so the digit positions pass the
+// Luhn check.
+func fixLuhnCheckDigit(out []rune, positions []int) {
+	sum := 0
+	double := true
+	for i := len(positions) - 2; i >= 0; i-- {
+		digit := int(out[positions[i]] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	out[positions[len(positions)-1]] = rune('0' + (10-sum%10)%10)
+}
+
+func enableFormatPreserving(cfg maskConfig) (maskConfig, error) {
+	if strings.TrimSpace(cfg.template) != "" {
+		return maskConfig{}, errors.New("-format-preserve cannot be combined with -mask-template or -hash; use -label-template for labels that need a template")
+	}
+	cfg.formatPreserve = true
+	return cfg, nil
+}
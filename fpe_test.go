@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestFormatPreservingSurrogateKeepsShape(t *testing.T) {
+	cases := map[string]string{
+		"phone":       "(555) 123-4567",
+		"email":       "Jane.Doe@example.com",
+		"credit_card": "4111 1111 1111 1111",
+		"ssn":         "123-45-6789",
+	}
+	for label, value := range cases {
+		got := formatPreservingSurrogate(label, value, "salt")
+		if len(got) != len(value) {
+			t.Fatalf("%s: expected length %d, got %q", label, len(value), got)
+		}
+		for i := range value {
+			if shapeOf(value[i]) != shapeOf(got[i]) {
+				t.Fatalf("%s: shape mismatch at %d: %q vs %q", label, i, value, got)
+			}
+		}
+		if got == value {
+			t.Fatalf("%s: expected a different surrogate for %q", label, value)
+		}
+		if again := formatPreservingSurrogate(label, value, "salt"); again != got {
+			t.Fatalf("%s: expected deterministic surrogate with key, got %q and %q", label, got, again)
+		}
+	}
+
+	card := formatPreservingSurrogate("credit_card", "4111 1111 1111 1111", "salt")
+	if !luhnValidToken(card) {
+		t.Fatalf("expected Luhn-valid card surrogate, got %q", card)
+	}
+	ssn := formatPreservingSurrogate("ssn", "123-45-6789", "salt")
+	if !regexp.MustCompile(`^9\d{2}-00-\d{4}$`).MatchString(ssn) {
+		t.Fatalf("expected never-issued SSN surrogate, got %q", ssn)
+	}
+}
+
+func TestRedactContentFormatPreserving(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = applyLabelTemplates(cfg, []string{"email=[EMAIL]"})
+	if err != nil {
+		t.Fatalf("label template error: %v", err)
+	}
+	cfg, err = enableFormatPreserving(cfg)
+	if err != nil {
+		t.Fatalf("format preserve error: %v", err)
+	}
+	redacted, counts := redactContent("Call 555-123-4567 or write a@b.org", patterns, cfg)
+	if !regexp.MustCompile(`^Call \d{3}-\d{3}-\d{4} or write \[EMAIL\]$`).MatchString(redacted) {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+	if counts["phone"] != 1 || counts["email"] != 1 {
+		t.Fatalf("unexpected counts: %#v", counts)
+	}
+
+	templated, err := buildMaskConfig("[REDACTED]", "[{label}]", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	if _, err := enableFormatPreserving(templated); err == nil {
+		t.Fatalf("expected error combining -format-preserve with -mask-template")
+	}
+}
+
+func shapeOf(b byte) byte {
+	switch {
+	case b >= '0' && b <= '9':
+		return '9'
+	case b >= 'a' && b <= 'z':
+		return 'a'
+	case b >= 'A' && b <= 'Z':
+		return 'A'
+	default:
+		return b
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed data/us_states.csv data/us_cities.csv
+var gazetteerData embed.FS
+
+type stateEntry struct {
+	name         string
+	abbreviation string
+}
+
+type cityEntry struct {
+	name       string
+	state      string
+	population int
+}
+
+type gazetteer struct {
+	states []stateEntry
+	cities []cityEntry
+	byAbbr map[string]string
+}
+
+var usGazetteer = mustLoadGazetteer()
+
+func mustLoadGazetteer() gazetteer {
+	g, err := loadGazetteer()
+	if err != nil {
+		panic("invalid embedded gazetteer: " + err.Error())
+	}
+	return g
+}
+
+func loadGazetteer() (gazetteer, error) {
+	g := gazetteer{byAbbr: map[string]string{}}
+	states, err := readGazetteerCSV("data/us_states.csv")
+	if err != nil {
+		return gazetteer{}, err
+	}
+	for _, row := range states {
+		entry := stateEntry{name: row[0], abbreviation: row[1]}
+		g.states = append(g.states, entry)
+		g.byAbbr[entry.abbreviation] = entry.name
+	}
+	cities, err := readGazetteerCSV("data/us_cities.csv")
+	if err != nil {
+		return gazetteer{}, err
+	}
+	for _, row := range cities {
+		population, err := strconv.Atoi(row[2])
+		if err != nil {
+			return gazetteer{}, fmt.Errorf("invalid population for %s: %w", row[0], err)
+		}
+		if _, ok := g.byAbbr[row[1]]; !ok {
+			return gazetteer{}, fmt.Errorf("unknown state %q for %s", row[1], row[0])
+		}
+		g.cities = append(g.cities, cityEntry{name: row[0], state: row[1], population: population})
+	}
+	return g, nil
+}
+
+func readGazetteerCSV(name string) ([][]string, error) {
+	file, err := gazetteerData.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: missing header", name)
+	}
+	return rows[1:], nil
+}
+
+// cityNames returns the distinct city names at or above minPopulation.
+func (g gazetteer) cityNames(minPopulation int) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, city := range g.cities {
+		if city.population < minPopulation || seen[city.name] {
+			continue
+		}
+		seen[city.name] = true
+		names = append(names, city.name)
+	}
+	return names
+}
+
+// cityState returns the full state name for a city, or false when the city
+// is unknown or shared by several states.
+func (g gazetteer) cityState(name string) (string, bool) {
+	state := ""
+	for _, city := range g.cities {
+		if city.name != name {
+			continue
+		}
+		if state != "" && state != city.state {
+			return "", false
+		}
+		state = city.state
+	}
+	if state == "" {
+		return "", false
+	}
+	return g.byAbbr[state], true
+}
+
+func buildLocationPatterns(minPopulation int, schools []string) []pattern {
+	abbreviations := make([]string, 0, len(usGazetteer.states))
+	stateNames := make([]string, 0, len(usGazetteer.states))
+	for _, state := range usGazetteer.states {
+		abbreviations = append(abbreviations, state.abbreviation)
+		stateNames = append(stateNames, state.name)
+	}
+
+	patterns := []pattern{
+		{label: "zip", re: regexp.MustCompile(`\b\d{5}-\d{4}\b`)},
+		{label: "zip", re: regexp.MustCompile(`\b(?:` + strings.Join(abbreviations, "|") + `),?\s+(\d{5})\b`), group: 1},
+		{label: "zip", re: regexp.MustCompile(`(?i)\bzip(?:\s*code)?\s*[:#]?\s*(\d{5})\b`), group: 1},
+	}
+	if cities := usGazetteer.cityNames(minPopulation); len(cities) > 0 {
+		patterns = append(patterns, pattern{label: "city", re: regexp.MustCompile(alternationPattern(cities, false)), fits: cityFits})
+	}
+	patterns = append(patterns,
+		pattern{label: "state", re: regexp.MustCompile(alternationPattern(stateNames, false)), fits: notInProperName},
+		pattern{label: "state", re: regexp.MustCompile(`,[ \t]*(` + strings.Join(abbreviations, "|") + `)\b`), group: 1, fits: stateAbbreviationFits},
+	)
+	if len(schools) > 0 {
+		patterns = append(patterns, pattern{label: "school", re: regexp.MustCompile(alternationPattern(schools, true))})
+	}
+	return patterns
+}
+
+// ambiguousStateAbbreviations double as everyday words ("Sure, OK", "Come
+// in, ME too") and are only trusted next to a ZIP or a known city.
+var ambiguousStateAbbreviations = map[string]bool{
+	"HI": true, "IN": true, "ME": true, "OH": true, "OK": true, "OR": true,
+}
+
+var (
+	zipAfterStateRe  = regexp.MustCompile(`^,?\s+\d{5}\b`)
+	placeBeforeComma = regexp.MustCompile(`\b[A-Z][A-Za-z.'’-]*$`)
+)
+
+// stateAbbreviationFits accepts ", IL" only after a capitalised place name,
+// and an ambiguous abbreviation only after a gazetteer city or before a ZIP.
+func stateAbbreviationFits(content string, start, end int) bool {
+	if zipAfterStateRe.MatchString(content[end:]) {
+		return true
+	}
+	place := strings.TrimRight(content[:start], " \t")
+	place = strings.TrimRight(strings.TrimSuffix(place, ","), " \t")
+	if !placeBeforeComma.MatchString(place) {
+		return false
+	}
+	if !ambiguousStateAbbreviations[content[start:end]] {
+		return true
+	}
+	return usGazetteer.endsWithCity(place)
+}
+
+var (
+	properNameAfterRe = regexp.MustCompile(`^[ \t]+[A-Z][a-z]`)
+	stateAfterCityRe  = regexp.MustCompile(`^,[ \t]*(?:` + strings.Join(stateNamesAndAbbreviations(), "|") + `)\b`)
+	placeBeforeCityRe = regexp.MustCompile(`(?i)\b(?:in|from|near|outside|around|visit(?:ed|ing|s)?|(?:move[sd]?|moving|relocated|returned|headed|back) to)[ \t]+$`)
+)
+
+// notInProperName rejects a place that starts a longer proper name, such as
+// "Columbia University", "Independence Day" or "Indiana Jones".
+func notInProperName(content string, start, end int) bool {
+	return !properNameAfterRe.MatchString(content[end:])
+}
+
+// cityFits only trusts a gazetteer city in place context: after "in", "from",
+// "moved to" and the like, or before a state or ZIP ("Mobile, AL 36602").
+// Many city names are also first names or ordinary words (Norman, Surprise,
+// Mobile), so a bare capitalised match is not enough.
+func cityFits(content string, start, end int) bool {
+	if !notInProperName(content, start, end) {
+		return false
+	}
+	after := content[end:]
+	if stateAfterCityRe.MatchString(after) || zipAfterStateRe.MatchString(after) {
+		return true
+	}
+	return placeBeforeCityRe.MatchString(content[:start])
+}
+
+func stateNamesAndAbbreviations() []string {
+	values := make([]string, 0, 2*len(usGazetteer.states))
+	for _, state := range usGazetteer.states {
+		values = append(values, regexp.QuoteMeta(state.name), state.abbreviation)
+	}
+	return values
+}
+
+// endsWithCity reports whether text ends with a whole gazetteer city name.
+func (g gazetteer) endsWithCity(text string) bool {
+	for _, city := range g.cities {
+		if !strings.HasSuffix(text, city.name) {
+			continue
+		}
+		last, _ := utf8.DecodeLastRuneInString(text[:len(text)-len(city.name)])
+		if last == utf8.RuneError || !unicode.IsLetter(last) && !unicode.IsDigit(last) {
+			return true
+		}
+	}
+	return false
+}
+
+// alternationPattern builds a word-bounded alternation with longer entries
+// first so "West Valley City" wins over "West Valley".
+func alternationPattern(values []string, ignoreCase bool) string {
+	sorted := append([]string{}, values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	quoted := make([]string, 0, len(sorted))
+	for _, value := range sorted {
+		quoted = append(quoted, regexp.QuoteMeta(value))
+	}
+	prefix := ""
+	if ignoreCase {
+		prefix = "(?i)"
+	}
+	return prefix + `\b(?:` + strings.Join(quoted, "|") + `)\b`
+}
+
+func generalizeCity(value string, cfg maskConfig) (string, bool) {
+	return usGazetteer.cityState(value)
+}
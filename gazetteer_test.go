@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildLocationPatternsRedactsPlaces(t *testing.T) {
+	patterns := buildLocationPatterns(100000, []string{"Lincoln Park High School"})
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "I grew up in Chicago, IL 60614 and attended lincoln park high school before moving to Texas (zip code 73301-0001)."
+	redacted, counts := redactContent(content, patterns, cfg)
+	want := "I grew up in [REDACTED], [REDACTED] [REDACTED] and attended [REDACTED] before moving to [REDACTED] (zip code [REDACTED])."
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+	expected := map[string]int{"city": 1, "state": 2, "zip": 2, "school": 1}
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Fatalf("expected %d %s redactions, got %#v", count, label, counts)
+		}
+	}
+}
+
+func TestBuildLocationPatternsPopulationThreshold(t *testing.T) {
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	_, counts := redactContent("Lived in Burlington and in Chicago.", buildLocationPatterns(100000, nil), cfg)
+	if counts["city"] != 1 {
+		t.Fatalf("expected only Chicago above threshold, got %#v", counts)
+	}
+	_, counts = redactContent("Lived in Burlington and in Chicago.", buildLocationPatterns(10000, nil), cfg)
+	if counts["city"] != 2 {
+		t.Fatalf("expected both cities with lower threshold, got %#v", counts)
+	}
+}
+
+func TestGeneralizeCityToState(t *testing.T) {
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = enableGeneralization(cfg, []string{"city"}, 3, 0)
+	if err != nil {
+		t.Fatalf("generalization error: %v", err)
+	}
+	patterns := buildLocationPatterns(100000, nil)
+	redacted, _ := redactContent("From Chicago, then from Springfield.", patterns, cfg)
+	if redacted != "From Illinois, then from [REDACTED]." {
+		t.Fatalf("unexpected generalized content: %q", redacted)
+	}
+}
+
+func TestCityNeedsPlaceContext(t *testing.T) {
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	patterns := buildLocationPatterns(100000, nil)
+
+	content := "Surprise! My Mobile phone rang on Independence Day. Elizabeth and Norman went to Columbia University to watch Indiana Jones."
+	if redacted, counts := redactContent(content, patterns, cfg); redacted != content {
+		t.Fatalf("expected names and words to survive, got %q (%#v)", redacted, counts)
+	}
+
+	content = "Norman moved to Mobile, then lived near Norman before visiting Columbia, SC and Surprise, Arizona."
+	redacted, counts := redactContent(content, patterns, cfg)
+	want := "Norman moved to [REDACTED], then lived near [REDACTED] before visiting [REDACTED], [REDACTED] and [REDACTED], [REDACTED]."
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+	if counts["city"] != 4 {
+		t.Fatalf("expected 4 city redactions, got %#v", counts)
+	}
+}
+
+func TestStateAbbreviationNeedsPlaceContext(t *testing.T) {
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	patterns := buildLocationPatterns(1000000, nil)
+
+	content := "Sure, OK. Come in, ME too. then, IL. Tulsa, OK and Norman, OK 73019 and Peoria, IL."
+	redacted, counts := redactContent(content, patterns, cfg)
+	want := "Sure, OK. Come in, ME too. then, IL. Tulsa, [REDACTED] and Norman, [REDACTED] [REDACTED] and Peoria, [REDACTED]."
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+	if counts["state"] != 3 {
+		t.Fatalf("expected 3 state redactions, got %#v", counts)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// generalizers replace a match with a coarser bucket instead of a mask. The
+// keys are the labels accepted by -generalize.
+var generalizers = map[string]func(value string, cfg maskConfig) (string, bool){
+	"age":       generalizeAge,
+	"city":      generalizeCity,
+	"grad_year": generalizeGradYear,
+}
+
+func enableGeneralization(cfg maskConfig, labels []string, ageBucket, yearBucket int) (maskConfig, error) {
+	if ageBucket <= 0 {
+		return maskConfig{}, errors.New("age-bucket must be greater than 0")
+	}
+	if yearBucket < 0 {
+		return maskConfig{}, errors.New("year-bucket must be 0 or greater")
+	}
+	enabled := map[string]bool{}
+	for _, raw := range labels {
+		for _, part := range strings.Split(raw, ",") {
+			label := strings.TrimSpace(part)
+			if label == "" {
+				continue
+			}
+			if _, ok := generalizers[label]; !ok {
+				return maskConfig{}, fmt.Errorf("cannot generalize %q (supported: %s)", label, strings.Join(generalizableLabels(), ", "))
+			}
+			enabled[label] = true
+		}
+	}
+	cfg.generalize = enabled
+	cfg.ageBucket = ageBucket
+	cfg.yearBucket = yearBucket
+	return cfg, nil
+}
+
+func generalizableLabels() []string {
+	labels := make([]string, 0, len(generalizers))
+	for label := range generalizers {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func generalizeValue(label, value string, cfg maskConfig) (string, bool) {
+	fn, ok := generalizers[label]
+	if !ok {
+		return "", false
+	}
+	return fn(value, cfg)
+}
+
+func generalizeAge(value string, cfg maskConfig) (string, bool) {
+	age, err := strconv.Atoi(value)
+	if err != nil {
+		return "", false
+	}
+	width := cfg.ageBucket
+	if width <= 1 {
+		return strconv.Itoa(age), true
+	}
+	start := age - age%width
+	return fmt.Sprintf("%d–%d", start, start+width-1), true
+}
+
+func generalizeGradYear(value string, cfg maskConfig) (string, bool) {
+	year, ok := parseGradYear(value)
+	if !ok {
+		return "", false
+	}
+	decade := year - year%10
+	switch width := cfg.yearBucket; {
+	case width == 0:
+		switch digit := year % 10; {
+		case digit <= 3:
+			return fmt.Sprintf("early-%ds", decade), true
+		case digit <= 6:
+			return fmt.Sprintf("mid-%ds", decade), true
+		default:
+			return fmt.Sprintf("late-%ds", decade), true
+		}
+	case width == 10:
+		return fmt.Sprintf("%ds", decade), true
+	case width == 1:
+		return strconv.Itoa(year), true
+	default:
+		start := year - year%width
+		return fmt.Sprintf("%d–%d", start, start+width-1), true
+	}
+}
+
+func parseGradYear(value string) (int, bool) {
+	trimmed := strings.TrimLeft(value, "'’")
+	year, err := strconv.Atoi(trimmed)
+	if err != nil {
+		return 0, false
+	}
+	if len(trimmed) == 2 {
+		year += 2000
+	}
+	return year, year >= 1950 && year <= 2099
+}
+
+func validAge(value string) bool {
+	age, err := strconv.Atoi(value)
+	return err == nil && age >= 5 && age <= 99
+}
+
+// notFollowedByUnit rejects an "I'm 10" style age when the number is really
+// a time or a quantity, as in "I'm 10 minutes away" or "turning 5:30".
+func notFollowedByUnit(content string, start, end int) bool {
+	return !quantityUnitRe.MatchString(content[end:])
+}
+
+var quantityUnitRe = regexp.MustCompile(`(?i)^(?:[:.]\d|\s*%|[\s-]*(?:minutes?|mins?|hours?|hrs?|seconds?|secs?|days?|weeks?|months?|miles?|mi|km|kilomet(?:er|re)s?|met(?:er|re)s?|feet|foot|ft|inch(?:es)?|pounds?|lbs?|kg|percent|points?|pts?|pages?|steps?|blocks?|o['’]clock)\b|\s*[ap]\.?m\b)`)
+
+func validGradYear(value string) bool {
+	_, ok := parseGradYear(value)
+	return ok
+}
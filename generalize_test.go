@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactContentGeneralizesAgeAndGradYear(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = enableGeneralization(cfg, []string{"age,grad_year"}, 3, 0)
+	if err != nil {
+		t.Fatalf("generalization error: %v", err)
+	}
+
+	content := "I'm 16 and a proud member of the Class of 2025. My 12-year-old brother helps."
+	redacted, counts := redactContent(content, patterns, cfg)
+	want := "I'm 15–17 and a proud member of the Class of mid-2020s. My 12–14-year-old brother helps."
+	if redacted != want {
+		t.Fatalf("unexpected generalized content:\n got %q\nwant %q", redacted, want)
+	}
+	if counts["age"] != 2 || counts["grad_year"] != 1 {
+		t.Fatalf("unexpected counts: %#v", counts)
+	}
+}
+
+func TestRedactContentMasksAgeWithoutGeneralization(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	redacted, _ := redactContent("I am 17 years old, graduating in 2026.", patterns, cfg)
+	if redacted != "I am [REDACTED] years old, graduating in [REDACTED]." {
+		t.Fatalf("unexpected masked content: %q", redacted)
+	}
+}
+
+func TestGeneralizeGradYearBucketWidths(t *testing.T) {
+	cases := []struct {
+		value string
+		width int
+		want  string
+	}{
+		{"2027", 0, "late-2020s"},
+		{"'21", 0, "early-2020s"},
+		{"2027", 10, "2020s"},
+		{"2027", 5, "2025–2029"},
+	}
+	for _, tc := range cases {
+		got, ok := generalizeGradYear(tc.value, maskConfig{yearBucket: tc.width})
+		if !ok || got != tc.want {
+			t.Fatalf("generalize %q width %d: got %q want %q", tc.value, tc.width, got, tc.want)
+		}
+	}
+	if _, err := enableGeneralization(maskConfig{}, []string{"email"}, 3, 0); err == nil || !strings.Contains(err.Error(), "cannot generalize") {
+		t.Fatalf("expected unsupported label error, got %v", err)
+	}
+}
+
+func TestAgePatternIgnoresTimesAndQuantities(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "I'm 10 minutes away, turning 5:30 into a habit, aged 12% more, and I am 6 a.m. sharp. I'm 17."
+	redacted, counts := redactContent(content, patterns, cfg)
+	if counts["age"] != 1 {
+		t.Fatalf("expected only the real age, got %#v in %q", counts, redacted)
+	}
+	if !strings.HasSuffix(redacted, "I'm [REDACTED].") || !strings.HasPrefix(redacted, "I'm 10 minutes away") {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//go:embed data/report.html
+var htmlReportTemplate string
+
+var htmlReport = template.Must(template.New("report").Parse(htmlReportTemplate))
+
+type htmlReportData struct {
+	Report report
+	Labels []htmlLabel
+	Files  []htmlFile
+}
+
+type htmlLabel struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+type htmlFile struct {
+	ID       string
+	Name     string
+	Total    int
+	State    string
+	Labels   []htmlLabel
+	Note     string
+	Segments []htmlSegment
+	Diff     [][]diffRow
+}
+
+// htmlSegment is a run of redacted text; Label is set for mask tokens so the
+// template can highlight them.
+type htmlSegment struct {
+	Text  string
+	Label string
+}
+
+// writeHTMLReport renders a single offline HTML file. Redacted text is read
+// back from each target; previews supplies it for dry runs, keyed by source.
+// With diffContext >= 0 each file page also gets a side-by-side diff against
+// the original, read from the source at render time.
+func writeHTMLReport(path string, rep report, previews map[string]string, diffContext int, modes outputModes) error {
+	data := htmlReportData{Report: rep, Labels: htmlLabels(rep.ByPattern)}
+	for i, entry := range rep.Details {
+		file := htmlFile{
+			ID:     fmt.Sprintf("file-%d", i+1),
+			Name:   displayPath(rep.InputPath, entry.Source),
+			Total:  entry.Total,
+			State:  fileState(entry),
+			Labels: htmlLabels(entry.Redactions),
+		}
+		text, ok := previews[entry.Source]
+		switch {
+		case ok:
+		case entry.Skipped:
+			file.Note = "No redactions; no output was written."
+		case entry.Target == "":
+			file.Note = "Redacted text is not available for this file."
+		default:
+			content, err := os.ReadFile(entry.Target)
+			if err != nil {
+				file.Note = "Redacted output could not be read: " + err.Error()
+			}
+			text = string(content)
+		}
+		if file.Note == "" {
+			file.Segments = highlightSegments(text, entry.Findings)
+		}
+		if file.Note == "" && diffContext >= 0 && entry.Total > 0 {
+			if original, err := os.ReadFile(entry.Source); err == nil {
+				for _, h := range diffHunks(string(original), text, entry.Findings, diffContext) {
+					file.Diff = append(file.Diff, sideBySideRows(h))
+				}
+			}
+		}
+		data.Files = append(data.Files, file)
+	}
+
+	return writeAtomic(path, modes.file, modes.dir, func(w io.Writer) error {
+		return htmlReport.Execute(w, data)
+	})
+}
+
+// htmlLabels sorts label counts descending and scales them for bar charts.
+func htmlLabels(counts map[string]int) []htmlLabel {
+	labels := make([]htmlLabel, 0, len(counts))
+	max := 0
+	for label, count := range counts {
+		if count == 0 {
+			continue
+		}
+		labels = append(labels, htmlLabel{Label: label, Count: count})
+		if count > max {
+			max = count
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Count != labels[j].Count {
+			return labels[i].Count > labels[j].Count
+		}
+		return labels[i].Label < labels[j].Label
+	})
+	for i := range labels {
+		labels[i].Percent = labels[i].Count * 100 / max
+	}
+	return labels
+}
+
+// highlightSegments splits redacted text at the recorded output spans. Spans
+// that no longer fit the text (for example an output edited by hand) are
+// ignored rather than highlighting the wrong characters.
+func highlightSegments(text string, findings []finding) []htmlSegment {
+	var segments []htmlSegment
+	last := 0
+	for _, f := range findings {
+		start, end := f.OutputOffset, f.OutputOffset+f.OutputLength
+		if start < last || end > len(text) || start == end {
+			continue
+		}
+		if start > last {
+			segments = append(segments, htmlSegment{Text: text[last:start]})
+		}
+		segments = append(segments, htmlSegment{Text: text[start:end], Label: f.Label})
+		last = end
+	}
+	if last < len(text) {
+		segments = append(segments, htmlSegment{Text: text[last:]})
+	}
+	return segments
+}
+
+func fileState(entry fileReport) string {
+	switch {
+	case entry.Skipped:
+		return "skipped (clean)"
+	case entry.Unchanged:
+		return "unchanged"
+	default:
+		return "redacted"
+	}
+}
+
+func displayPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && rel != "." && withinDir(root, path) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.Base(path)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHighlightSegments(t *testing.T) {
+	text := "Email [REDACTED] or call [REDACTED]."
+	findings := []finding{
+		{Label: "email", OutputOffset: 6, OutputLength: 10},
+		{Label: "phone", OutputOffset: 25, OutputLength: 10},
+		{Label: "stale", OutputOffset: 30, OutputLength: 40},
+	}
+	got := highlightSegments(text, findings)
+	want := []htmlSegment{
+		{Text: "Email "},
+		{Text: "[REDACTED]", Label: "email"},
+		{Text: " or call "},
+		{Text: "[REDACTED]", Label: "phone"},
+		{Text: "."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d segments, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("segment %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestWriteHTMLReportIsSelfContained(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "in")
+	mustMkdir(t, input)
+	source := filepath.Join(input, "essay.txt")
+	mustWrite(t, source, "Email jane@example.com <script>")
+
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	entry, content, err := redactFile(source, input, "", patterns, cfg, true, false, defaultOutputModes)
+	if err != nil {
+		t.Fatalf("redactFile error: %v", err)
+	}
+	rep := report{InputPath: input, Status: statusComplete, ByPattern: map[string]int{}}
+	rep.add(entry)
+
+	path := filepath.Join(root, "report.html")
+	if err := writeHTMLReport(path, rep, map[string]string{source: content}, -1, defaultOutputModes); err != nil {
+		t.Fatalf("writeHTMLReport error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{`<mark title="email">[REDACTED]</mark>`, "&lt;script&gt;", `href="#file-1"`, "essay.txt"} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("expected HTML to contain %q", want)
+		}
+	}
+	for _, forbidden := range []string{"jane@example.com", "src=\"http", "href=\"http", "<link"} {
+		if strings.Contains(html, forbidden) {
+			t.Fatalf("expected HTML to not contain %q", forbidden)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// identifierKeyword matches the optional "number"/"no."/"#" wording and
+// separator that usually sits between an identifier's name and its value.
+const identifierKeyword = `(?:\s+(?:number|num|no\.?|#))?\s*[:#]?\s*`
+
+// optionalPatterns are off by default and enabled with -enable-pattern. They
+// run ahead of the default patterns so, for example, an ITIN is labeled
+// id:itin rather than ssn.
+func optionalPatterns() []pattern {
+	return []pattern{
+		{label: "id:itin", re: regexp.MustCompile(`\b9\d{2}-?(?:5\d|6[0-5]|7\d|8[0-8]|9[0-2]|9[4-9])-?\d{4}\b`)},
+		{label: "id:ein", re: regexp.MustCompile(`\b\d{2}-\d{7}\b`), validate: validEIN},
+		{label: "id:aba_routing", re: regexp.MustCompile(`(?i)\b(?:routing|aba|rtn)` + identifierKeyword + `(\d{9})\b`), group: 1, validate: validABARouting},
+		{label: "id:bank_account", re: regexp.MustCompile(`(?i)\b(?:bank\s+)?(?:account|acct)\.?` + identifierKeyword + `(\d{6,17})\b`), group: 1},
+		{label: "id:passport", re: regexp.MustCompile(`(?i)\bpassport` + identifierKeyword + `([A-Z0-9]{9})\b`), group: 1, validate: validUSPassport},
+		{label: "id:drivers_license", re: regexp.MustCompile(`(?i)\b(?:driver'?s?\s+licen[cs]e|DL|license)` + identifierKeyword + `([A-Z0-9*\-]{4,16})\b`), group: 1, validate: validDriversLicense},
+		{label: "id:medicare_mbi", re: regexp.MustCompile(`\b[1-9][AC-HJKMNP-RT-Yac-hjkmnp-rt-y][AC-HJKMNP-RT-Yac-hjkmnp-rt-y0-9]\d-?[AC-HJKMNP-RT-Yac-hjkmnp-rt-y][AC-HJKMNP-RT-Yac-hjkmnp-rt-y0-9]\d-?[AC-HJKMNP-RT-Yac-hjkmnp-rt-y]{2}\d{2}\b`)},
+		{label: "id:fsa_id", re: regexp.MustCompile(`(?i)\bFSA\s*ID(?:\s+username)?(?:\s*[:#]\s*|\s+(?:is|was)\s+)([A-Za-z0-9._\-]{6,30})\b`), group: 1},
+	}
+}
+
+// validEINPrefixes lists the campus prefixes the IRS assigns to EINs.
+var validEINPrefixes = func() map[string]bool {
+	prefixes := map[string]bool{}
+	ranges := [][2]int{{1, 6}, {10, 16}, {20, 27}, {30, 48}, {50, 68}, {71, 77}, {80, 88}, {90, 95}, {98, 99}}
+	for _, r := range ranges {
+		for n := r[0]; n <= r[1]; n++ {
+			prefixes[fmt.Sprintf("%02d", n)] = true
+		}
+	}
+	return prefixes
+}()
+
+func validEIN(value string) bool {
+	return len(value) >= 2 && validEINPrefixes[value[:2]]
+}
+
+// validABARouting checks the routing number prefix ranges and the 3-7-1
+// weighted checksum.
+func validABARouting(value string) bool {
+	if len(value) != 9 {
+		return false
+	}
+	prefix := int(value[0]-'0')*10 + int(value[1]-'0')
+	if !(prefix <= 12 || (prefix >= 21 && prefix <= 32) || (prefix >= 61 && prefix <= 72) || prefix == 80) {
+		return false
+	}
+	weights := []int{3, 7, 1}
+	sum := 0
+	for i := 0; i < 9; i++ {
+		ch := value[i]
+		if ch < '0' || ch > '9' {
+			return false
+		}
+		sum += int(ch-'0') * weights[i%3]
+	}
+	return sum%10 == 0
+}
+
+var usPassportRe = regexp.MustCompile(`^(?:\d{9}|[A-Z]\d{8})$`)
+
+func validUSPassport(value string) bool {
+	return usPassportRe.MatchString(strings.ToUpper(value))
+}
+
+// driversLicenseFormats covers the common state license number shapes. A
+// value near a license keyword must fit at least one of them.
+var driversLicenseFormats = []*regexp.Regexp{
+	regexp.MustCompile(`^[A-Z]\d{7}$`),                      // CA, WA (older), NV variants
+	regexp.MustCompile(`^\d{9}$`),                           // NY, NJ-style numeric, many states
+	regexp.MustCompile(`^\d{8}$`),                           // TX, PA
+	regexp.MustCompile(`^\d{7}$`),                           // AL, DE, ME
+	regexp.MustCompile(`^[A-Z]\d{12}$`),                     // FL, MD, MI, MN
+	regexp.MustCompile(`^[A-Z]\d{11}$`),                     // IL
+	regexp.MustCompile(`^[A-Z]\d{8}$`),                      // AZ, MA, OH variants
+	regexp.MustCompile(`^[A-Z]{2}\d{6}$`),                   // OH, VT variants
+	regexp.MustCompile(`^[A-Z]\d{2}-\d{2}-\d{4}$`),          // KS, VA formatted
+	regexp.MustCompile(`^[A-Z]\d{3}-\d{3}-\d{2}-\d{3}-\d$`), // FL formatted
+	regexp.MustCompile(`^[A-Z]\d{3}-\d{4}-\d{4}$`),          // IL formatted
+	regexp.MustCompile(`^[A-Z*]{5}[A-Z*\d]{3}[A-Z\d]{4}$`),  // WA
+	regexp.MustCompile(`^\d{3}[A-Z]{2}\d{4}$`),              // IA
+	regexp.MustCompile(`^[A-Z]{1,2}\d{5,7}$`),               // misc alpha-prefixed formats
+}
+
+func validDriversLicense(value string) bool {
+	upper := strings.ToUpper(value)
+	if !strings.ContainsAny(upper, "0123456789") {
+		return false
+	}
+	for _, format := range driversLicenseFormats {
+		if format.MatchString(upper) {
+			return true
+		}
+	}
+	return false
+}
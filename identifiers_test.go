@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectOptionalPatterns(t *testing.T) {
+	selected, err := selectOptionalPatterns(optionalPatterns(), []string{"id:itin", "id:aba*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(selected) != 2 || selected[0].label != "id:itin" || selected[1].label != "id:aba_routing" {
+		t.Fatalf("unexpected selection: %#v", selected)
+	}
+	all, err := selectOptionalPatterns(optionalPatterns(), []string{"id:*"})
+	if err != nil || len(all) != len(optionalPatterns()) {
+		t.Fatalf("expected every identifier pattern, got %d (%v)", len(all), err)
+	}
+	if _, err := selectOptionalPatterns(optionalPatterns(), []string{"id:unknown"}); err == nil {
+		t.Fatalf("expected error for unknown optional pattern")
+	}
+}
+
+func TestIdentifierPackRedactsWithValidators(t *testing.T) {
+	optional, err := selectOptionalPatterns(optionalPatterns(), []string{"id:*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	patterns := append(optional, defaults...)
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "ITIN 912-70-1234, EIN 12-3456789, routing number 021000021, bad routing 021000022, " +
+		"account #: 000123456789, passport no. C12345678, driver's license: D1234567, MBI 1EG4-TE5-MK73, FSA ID: jdoe2007"
+	_, counts := redactContent(content, patterns, cfg)
+	expected := map[string]int{
+		"id:itin":            1,
+		"id:ein":             1,
+		"id:aba_routing":     1,
+		"id:bank_account":    1,
+		"id:passport":        1,
+		"id:drivers_license": 1,
+		"id:medicare_mbi":    1,
+		"id:fsa_id":          1,
+	}
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Fatalf("expected %d %s, got %#v", count, label, counts)
+		}
+	}
+	if counts["ssn"] != 0 {
+		t.Fatalf("expected ITIN to win over ssn, got %#v", counts)
+	}
+}
+
+func TestValidABARouting(t *testing.T) {
+	if !validABARouting("011000015") {
+		t.Fatalf("expected valid routing number")
+	}
+	if validABARouting("011000016") || validABARouting("991000015") {
+		t.Fatalf("expected invalid routing numbers to fail")
+	}
+}
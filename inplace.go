@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// inPlaceConfig is what an -in-place run needs beyond the redaction config.
+// backupDir is this run's backup directory, empty when backups are off.
+type inPlaceConfig struct {
+	root      string
+	backupDir string
+	keys      bundleKeys
+	dryRun    bool
+}
+
+// redactInPlace overwrites a source with its redacted text. Files without
+// findings are left untouched and reported as skipped. When backups are on,
+// the original is sealed into <backupDir>/<relative path>.gsb before the
+// source is replaced; a failed backup leaves the source as it was.
+func redactInPlace(path string, patterns []pattern, maskCfg maskConfig, cfg inPlaceConfig) (fileReport, string, error) {
+	entry, redacted, err := redactFile(path, cfg.root, "", patterns, maskCfg, true, false, defaultOutputModes)
+	if err != nil {
+		return fileReport{}, "", err
+	}
+	entry.Target = path
+	if entry.Total == 0 {
+		entry.Skipped = true
+		return entry, redacted, nil
+	}
+	name := displayPath(cfg.root, path)
+	if cfg.backupDir != "" {
+		entry.Backup = filepath.Join(cfg.backupDir, filepath.FromSlash(name)+".gsb")
+	}
+	if cfg.dryRun {
+		return entry, redacted, nil
+	}
+
+	// A symlinked source is redacted through to the file it points at;
+	// renaming over the link itself would leave the original text in place.
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileReport{}, "", stageError{stage: "read", err: err}
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return fileReport{}, "", stageError{stage: "read", err: err}
+	}
+	if entry.Backup != "" {
+		original, err := os.ReadFile(target)
+		if err != nil {
+			return fileReport{}, "", stageError{stage: "read", err: err}
+		}
+		if err := writeBackup(entry.Backup, filepath.Base(path), info, original, cfg.keys); err != nil {
+			return fileReport{}, "", stageError{stage: "backup", err: err}
+		}
+	}
+	// The source keeps its own permissions; -file-mode is for new outputs.
+	modes := outputModes{file: info.Mode().Perm(), dir: defaultOutputModes.dir}
+	if err := writeFileAtomic(target, []byte(redacted), modes); err != nil {
+		return fileReport{}, "", stageError{stage: "write", err: err}
+	}
+	return entry, redacted, nil
+}
+
+// confirmInPlace asks the operator to type "yes" before sources are
+// overwritten. The answer is read a byte at a time so nothing meant for the
+// -interactive prompts that follow is consumed.
+func confirmInPlace(in io.Reader, out io.Writer, root string, files int, backupDir string) bool {
+	fmt.Fprintf(out, "About to redact up to %d files in place under %s.\n", files, root)
+	if backupDir == "" {
+		fmt.Fprintln(out, "No backups will be written; the original text cannot be recovered.")
+	} else {
+		fmt.Fprintf(out, "Encrypted backups of changed files will be written to %s.\n", backupDir)
+	}
+	fmt.Fprint(out, "Type yes to continue: ")
+	var answer strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n == 1 && buf[0] != '\n' {
+			answer.WriteByte(buf[0])
+		}
+		if err != nil || (n == 1 && buf[0] == '\n') {
+			break
+		}
+	}
+	return strings.TrimSpace(answer.String()) == "yes"
+}
+
+// stdinIsTerminal reports whether a person can answer a prompt on stdin.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printInPlacePreview lists what a dry run with -in-place would change.
+func printInPlacePreview(w io.Writer, rep report) {
+	for _, entry := range rep.Details {
+		if entry.Skipped {
+			continue
+		}
+		line := fmt.Sprintf("Would overwrite %s (redactions: %d)", displayPath(rep.InputPath, entry.Source), entry.Total)
+		if entry.Backup != "" {
+			line += ", backup " + entry.Backup
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactInPlaceWithBackup(t *testing.T) {
+	root := t.TempDir()
+	essay := filepath.Join(root, "essay.txt")
+	clean := filepath.Join(root, "clean.txt")
+	mustWrite(t, essay, "Email jane@example.com")
+	mustWrite(t, clean, "Nothing to see")
+	if err := os.Chmod(essay, 0o640); err != nil {
+		t.Fatalf("chmod error: %v", err)
+	}
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	backups := filepath.Join(t.TempDir(), "run")
+	inPlace := inPlaceConfig{root: root, backupDir: backups, keys: bundleKeys{passphrase: "correct horse"}, dryRun: true}
+
+	preview, _, err := redactInPlace(essay, patterns, cfg, inPlace)
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if data, _ := os.ReadFile(essay); string(data) != "Email jane@example.com" || preview.Backup != filepath.Join(backups, "essay.txt.gsb") {
+		t.Fatalf("expected dry run to plan a backup without writing, got %q %+v", data, preview)
+	}
+	if _, err := os.Stat(backups); !os.IsNotExist(err) {
+		t.Fatalf("expected no backup directory after a dry run")
+	}
+
+	inPlace.dryRun = false
+	entry, _, err := redactInPlace(essay, patterns, cfg, inPlace)
+	if err != nil {
+		t.Fatalf("redactInPlace error: %v", err)
+	}
+	info, _ := os.Stat(essay)
+	if data, _ := os.ReadFile(essay); string(data) != "Email [REDACTED]" || info.Mode().Perm() != 0o640 || entry.Target != essay {
+		t.Fatalf("expected the source redacted with its mode kept, got %q %v %+v", data, info.Mode().Perm(), entry)
+	}
+	restored := t.TempDir()
+	if _, err := unpackBundle(entry.Backup, restored, inPlace.keys, defaultOutputModes); err != nil {
+		t.Fatalf("unpack backup: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(restored, "essay.txt")); string(data) != "Email jane@example.com" {
+		t.Fatalf("expected the backup to hold the original, got %q", data)
+	}
+
+	skipped, _, err := redactInPlace(clean, patterns, cfg, inPlace)
+	if err != nil || !skipped.Skipped || skipped.Backup != "" {
+		t.Fatalf("expected a clean file to be skipped without backup, got %+v %v", skipped, err)
+	}
+}
+
+func TestConfirmInPlaceReadsOneLine(t *testing.T) {
+	var out strings.Builder
+	in := strings.NewReader("yes\na\n")
+	if !confirmInPlace(in, &out, "/essays", 3, "") {
+		t.Fatalf("expected yes to confirm")
+	}
+	if rest := in.Len(); rest != 2 {
+		t.Fatalf("expected the next answer to be left unread, %d bytes remain", rest)
+	}
+	if !strings.Contains(out.String(), "cannot be recovered") {
+		t.Fatalf("expected a warning about missing backups, got %q", out.String())
+	}
+	for _, answer := range []string{"y\n", "YES\n", ""} {
+		if confirmInPlace(strings.NewReader(answer), &out, "/essays", 3, "/backups") {
+			t.Fatalf("expected %q not to confirm", answer)
+		}
+	}
+}
+
+func TestRedactInPlaceFollowsSymlinks(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "drafts", "essay.txt")
+	mustMkdir(t, filepath.Join(root, "drafts"))
+	mustWrite(t, target, "Email jane@example.com")
+	link := filepath.Join(root, "essay.txt")
+	if err := os.Symlink(filepath.Join("drafts", "essay.txt"), link); err != nil {
+		t.Fatalf("symlink error: %v", err)
+	}
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	if _, _, err := redactInPlace(link, patterns, cfg, inPlaceConfig{root: root}); err != nil {
+		t.Fatalf("redactInPlace error: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "Email [REDACTED]" {
+		t.Fatalf("expected the link target to be redacted, got %q", data)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected the symlink to be kept, got %v %v", info, err)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	integrityName      = "integrity-manifest.json"
+	integrityVersion   = 1
+	integrityAlgorithm = "ed25519"
+)
+
+// integrityEnvelope is the file written next to the outputs. Manifest holds
+// the exact bytes that were signed, so verification never depends on how the
+// JSON is re-encoded.
+type integrityEnvelope struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm"`
+	KeyID     string          `json:"key_id"`
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"`
+}
+
+// integrityManifest lists every file in the output directory. Paths are
+// slash-separated and relative to that directory; the report is listed
+// separately because it may be written elsewhere.
+type integrityManifest struct {
+	GeneratedAt string          `json:"generated_at"`
+	KeyID       string          `json:"key_id"`
+	Report      *integrityFile  `json:"report,omitempty"`
+	Files       []integrityFile `json:"files"`
+}
+
+type integrityFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// writeIntegrityManifest hashes the output tree and the report and signs the
+// result. It runs last so every report written into the output directory is
+// covered. When only is non-nil, just those files are listed, so a bundle
+// verifies once unpacked.
+func writeIntegrityManifest(outputRoot, reportPath string, only []string, key ed25519.PrivateKey, modes outputModes) (string, error) {
+	manifestPath := filepath.Join(outputRoot, integrityName)
+	var files []integrityFile
+	var err error
+	if only != nil {
+		files, err = hashFiles(outputRoot, only)
+	} else {
+		files, err = hashTree(outputRoot, manifestPath)
+	}
+	if err != nil {
+		return "", err
+	}
+	keyID := publicKeyID(key.Public().(ed25519.PublicKey))
+	m := integrityManifest{GeneratedAt: time.Now().Format(time.RFC3339), KeyID: keyID, Files: files}
+	if reportPath != "" {
+		reportPath = absOrSelf(reportPath)
+		entry, err := hashFile(reportPath)
+		if err != nil {
+			return "", err
+		}
+		entry.Path = manifestPathFor(outputRoot, reportPath)
+		m.Report = &entry
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	envelope := integrityEnvelope{
+		Version:   integrityVersion,
+		Algorithm: integrityAlgorithm,
+		KeyID:     keyID,
+		Manifest:  payload,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return manifestPath, writeFileAtomic(manifestPath, data, modes)
+}
+
+// verifyIntegrity checks a delivered folder against a signed manifest and
+// returns one line per problem. An error means the manifest itself could not
+// be trusted or read.
+func verifyIntegrity(root, manifestPath, reportPath string, pub ed25519.PublicKey) ([]string, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var envelope integrityEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid integrity manifest %s: %w", manifestPath, err)
+	}
+	if envelope.Version != integrityVersion || envelope.Algorithm != integrityAlgorithm {
+		return nil, fmt.Errorf("unsupported integrity manifest version %d (%s)", envelope.Version, envelope.Algorithm)
+	}
+	if keyID := publicKeyID(pub); envelope.KeyID != keyID {
+		return nil, fmt.Errorf("manifest was signed with key %s but the public key is %s", envelope.KeyID, keyID)
+	}
+	// MarshalIndent re-indents the embedded manifest; compacting restores
+	// the signed bytes.
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, envelope.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid integrity manifest %s: %w", manifestPath, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signature)
+	if err != nil || !ed25519.Verify(pub, payload.Bytes(), signature) {
+		return nil, errors.New("integrity manifest signature is not valid")
+	}
+	var m integrityManifest
+	if err := json.Unmarshal(payload.Bytes(), &m); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	expected := map[string]integrityFile{}
+	for _, f := range m.Files {
+		expected[f.Path] = f
+	}
+	actual, err := hashTree(root, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range actual {
+		want, ok := expected[f.Path]
+		switch {
+		case !ok:
+			problems = append(problems, "unlisted: "+f.Path)
+		case want.Size != f.Size || want.SHA256 != f.SHA256:
+			problems = append(problems, "modified: "+f.Path)
+		}
+		delete(expected, f.Path)
+	}
+	for _, f := range m.Files {
+		if _, missing := expected[f.Path]; missing {
+			problems = append(problems, "missing: "+f.Path)
+		}
+	}
+
+	if m.Report != nil {
+		if reportPath == "" {
+			reportPath = m.Report.Path
+			if !filepath.IsAbs(reportPath) {
+				reportPath = filepath.Join(root, filepath.FromSlash(reportPath))
+			}
+		}
+		got, err := hashFile(reportPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, "missing report: "+reportPath)
+		case err != nil:
+			return nil, err
+		case got.Size != m.Report.Size || got.SHA256 != m.Report.SHA256:
+			problems = append(problems, "modified report: "+reportPath)
+		}
+	}
+	return problems, nil
+}
+
+// hashTree lists every regular file under root except skip, sorted by path.
+func hashTree(root, skip string) ([]integrityFile, error) {
+	files := []integrityFile{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || path == skip {
+			return nil
+		}
+		entry, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		entry.Path = manifestPathFor(root, path)
+		files = append(files, entry)
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+// hashFiles hashes the given files under root, sorted by path.
+func hashFiles(root string, paths []string) ([]integrityFile, error) {
+	files := make([]integrityFile, 0, len(paths))
+	for _, path := range paths {
+		entry, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entry.Path = manifestPathFor(root, path)
+		files = append(files, entry)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func hashFile(path string) (integrityFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return integrityFile{}, err
+	}
+	return integrityFile{Path: path, SHA256: contentSHA256(data), Size: int64(len(data))}, nil
+}
+
+// manifestPathFor returns path relative to root with forward slashes, or the
+// absolute path when it lies outside root.
+func manifestPathFor(root, path string) string {
+	if withinDir(root, path) {
+		rel, _ := filepath.Rel(root, path)
+		return filepath.ToSlash(rel)
+	}
+	return absOrSelf(path)
+}
+
+// publicKeyID is a short fingerprint of a raw public key, recorded in
+// manifests and bundles so the holder can tell which key to use.
+func publicKeyID(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadSigningKey reads a PKCS #8 PEM Ed25519 private key, as written by
+// keygen.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return key, nil
+}
+
+// loadVerifyKey reads a PKIX PEM Ed25519 public key. A private key file is
+// accepted too, for verifying on the machine that signed.
+func loadVerifyKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "")
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public().(ed25519.PublicKey), nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return key, nil
+}
+
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	if blockType != "" && block.Type != blockType {
+		return nil, fmt.Errorf("%s contains a %s, expected a %s", path, block.Type, blockType)
+	}
+	return block, nil
+}
+
+// runKeygen writes a new key pair: the private key with owner-only
+// permissions and the public key next to it with a .pub suffix. Ed25519 keys
+// sign integrity manifests; X25519 keys receive encrypted bundles.
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	outPath := fs.String("out", "", "Path for the private key; the public key is written to <out>.pub")
+	keyType := fs.String("type", "ed25519", "Key type: ed25519 (signing) or x25519 (bundle recipient)")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*outPath) == "" {
+		return errors.New("keygen requires -out")
+	}
+	var priv, pub any
+	var rawPub []byte
+	switch *keyType {
+	case "ed25519":
+		edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		priv, pub, rawPub = edPriv, edPub, edPub
+	case "x25519":
+		xPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		priv, pub, rawPub = xPriv, xPriv.PublicKey(), xPriv.PublicKey().Bytes()
+	default:
+		return fmt.Errorf("unknown key type %q (expected ed25519 or x25519)", *keyType)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	privFile, err := os.OpenFile(*outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create private key: %w", err)
+	}
+	if err := pem.Encode(privFile, &pem.Block{Type: "PRIVATE KEY", Bytes: privDER}); err != nil {
+		privFile.Close()
+		return err
+	}
+	if err := privFile.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*outPath+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s %s and %s.pub (key ID %s)\n", *keyType, *outPath, *outPath, publicKeyID(rawPub))
+	return nil
+}
+
+// runVerifyManifest checks a delivered output folder against its signed
+// integrity manifest.
+func runVerifyManifest(args []string) error {
+	fs := flag.NewFlagSet("verify-manifest", flag.ExitOnError)
+	dir := fs.String("dir", "", "Delivered output directory")
+	pubKeyPath := fs.String("pub-key", "", "Ed25519 public key (PEM) of the signer")
+	manifestPath := fs.String("manifest", "", "Integrity manifest (default: <dir>/"+integrityName+")")
+	reportPath := fs.String("report", "", "Report to check when it was delivered outside <dir>")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*dir) == "" || strings.TrimSpace(*pubKeyPath) == "" {
+		return errors.New("verify-manifest requires -dir and -pub-key")
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+	if *manifestPath == "" {
+		*manifestPath = filepath.Join(root, integrityName)
+	} else if *manifestPath, err = filepath.Abs(*manifestPath); err != nil {
+		return err
+	}
+	pub, err := loadVerifyKey(*pubKeyPath)
+	if err != nil {
+		return err
+	}
+	problems, err := verifyIntegrity(root, *manifestPath, *reportPath, pub)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("verification failed: %d problem(s) in %s", len(problems), root)
+	}
+	fmt.Printf("Verified %s against %s (key ID %s)\n", root, *manifestPath, publicKeyID(pub))
+	return nil
+}
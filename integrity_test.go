@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityManifestDetectsChanges(t *testing.T) {
+	root := t.TempDir()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("keygen error: %v", err)
+	}
+	mustWrite(t, filepath.Join(root, "a.txt"), "[REDACTED]")
+	mustMkdir(t, filepath.Join(root, "nested"))
+	mustWrite(t, filepath.Join(root, "nested", "b.txt"), "clean")
+	reportPath := filepath.Join(t.TempDir(), "redaction-report.json")
+	mustWrite(t, reportPath, "{}")
+
+	manifestPath, err := writeIntegrityManifest(root, reportPath, nil, key, defaultOutputModes)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	problems, err := verifyIntegrity(root, manifestPath, "", pub)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("expected clean verification, got %v %v", problems, err)
+	}
+
+	mustWrite(t, filepath.Join(root, "a.txt"), "jane@example.com")
+	os.Remove(filepath.Join(root, "nested", "b.txt"))
+	mustWrite(t, filepath.Join(root, "extra.txt"), "added later")
+	mustWrite(t, reportPath, "{ }")
+	problems, err = verifyIntegrity(root, manifestPath, "", pub)
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	want := []string{"modified: a.txt", "unlisted: extra.txt", "missing: nested/b.txt", "modified report: " + reportPath}
+	if !reflect.DeepEqual(problems, want) {
+		t.Fatalf("expected %v, got %v", want, problems)
+	}
+}
+
+func TestIntegrityManifestRejectsForgedSignature(t *testing.T) {
+	root := t.TempDir()
+	_, key, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	mustWrite(t, filepath.Join(root, "a.txt"), "[REDACTED]")
+	manifestPath, err := writeIntegrityManifest(root, "", nil, key, defaultOutputModes)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if _, err := verifyIntegrity(root, manifestPath, "", otherPub); err == nil {
+		t.Fatalf("expected a key mismatch error")
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	// Claim a different hash for a.txt, as someone covering up an edit would.
+	original := contentSHA256([]byte("[REDACTED]"))
+	mustWrite(t, filepath.Join(root, "a.txt"), "edited")
+	mustWrite(t, manifestPath, strings.Replace(string(data), original, contentSHA256([]byte("edited")), 1))
+	if _, err := verifyIntegrity(root, manifestPath, "", key.Public().(ed25519.PublicKey)); err == nil {
+		t.Fatalf("expected an edited manifest to fail signature verification")
+	}
+}
+
+func TestKeygenWritesLoadableKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signing.key")
+	if err := runKeygen([]string{"-out", path}); err != nil {
+		t.Fatalf("keygen error: %v", err)
+	}
+	priv, err := loadSigningKey(path)
+	if err != nil {
+		t.Fatalf("load private key: %v", err)
+	}
+	pub, err := loadVerifyKey(path + ".pub")
+	if err != nil {
+		t.Fatalf("load public key: %v", err)
+	}
+	if !pub.Equal(priv.Public()) {
+		t.Fatalf("public key does not match private key")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected private key mode 0600, got %v", info.Mode().Perm())
+	}
+	if err := runKeygen([]string{"-out", path}); err == nil {
+		t.Fatalf("expected keygen to refuse to overwrite an existing key")
+	}
+}
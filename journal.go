@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	journalName    = "redaction-journal.jsonl"
+	journalVersion = 1
+)
+
+// journalHeader is the first line of the journal. A resumed run must match
+// the input and configuration fingerprint of the run that wrote it.
+type journalHeader struct {
+	Journal     int    `json:"journal"`
+	StartedAt   string `json:"started_at"`
+	InputPath   string `json:"input_path"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// journal appends one line per finished file so an interrupted run can pick
+// up where it stopped. It is removed once the run completes.
+type journal struct {
+	path string
+	file *os.File
+	enc  *json.Encoder
+}
+
+// openJournal starts a fresh journal, or with resume reloads an existing one
+// and returns the entries it already recorded keyed by source path.
+func openJournal(path string, header journalHeader, resume bool) (*journal, map[string]manifestEntry, error) {
+	done := map[string]manifestEntry{}
+	if resume {
+		existing, entries, err := readJournal(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("nothing to resume: %s not found", path)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing.InputPath != header.InputPath || existing.Fingerprint != header.Fingerprint {
+			return nil, nil, errors.New("cannot resume: input or configuration changed since the interrupted run; rerun without -resume")
+		}
+		done = entries
+		header.StartedAt = existing.StartedAt
+	}
+
+	header.Journal = journalVersion
+	if header.StartedAt == "" {
+		header.StartedAt = time.Now().Format(time.RFC3339)
+	}
+	// The journal is rewritten rather than appended to so a line torn by a
+	// crash does not corrupt the entries recorded after resuming. The rewrite
+	// goes through a temporary file so a crash mid-way keeps the old journal.
+	err := writeAtomic(path, 0o600, defaultOutputModes.dir, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+		for _, entry := range done {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	j := &journal{path: path, file: file, enc: json.NewEncoder(file)}
+	return j, done, nil
+}
+
+// readJournal parses a journal. A torn final line from a crash is ignored.
+func readJournal(path string) (journalHeader, map[string]manifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return journalHeader{}, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return journalHeader{}, nil, fmt.Errorf("journal %s is empty", path)
+	}
+	var header journalHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Journal == 0 {
+		return journalHeader{}, nil, fmt.Errorf("journal %s has no valid header", path)
+	}
+	entries := map[string]manifestEntry{}
+	for scanner.Scan() {
+		var entry manifestEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break
+		}
+		entries[entry.Report.Source] = entry
+	}
+	return header, entries, scanner.Err()
+}
+
+func (j *journal) record(entry manifestEntry) error {
+	return j.enc.Encode(entry)
+}
+
+// finish closes the journal and removes it after a completed run.
+func (j *journal) finish() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.path)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalResumesRecordedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), journalName)
+	header := journalHeader{InputPath: "/in", Fingerprint: "fp"}
+	j, _, err := openJournal(path, header, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.record(manifestEntry{Report: fileReport{Source: "/in/a.txt", Total: 2}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	j.file.Close()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	file.WriteString(`{"report":{"source":"/in/b.t`)
+	file.Close()
+
+	j, done, err := openJournal(path, header, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(done) != 1 || done["/in/a.txt"].Report.Total != 2 {
+		t.Fatalf("expected one recorded entry ignoring the torn line, got %+v", done)
+	}
+	if err := j.record(manifestEntry{Report: fileReport{Source: "/in/c.txt"}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	j.file.Close()
+	if _, entries, err := readJournal(path); err != nil || len(entries) != 2 {
+		t.Fatalf("expected two entries after resuming, got %d (%v)", len(entries), err)
+	}
+}
+
+func TestJournalRejectsChangedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), journalName)
+	j, _, err := openJournal(path, journalHeader{InputPath: "/in", Fingerprint: "fp"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	j.file.Close()
+	if _, _, err := openJournal(path, journalHeader{InputPath: "/in", Fingerprint: "other"}, true); err == nil {
+		t.Fatalf("expected error when configuration changed")
+	}
+	if _, _, err := openJournal(filepath.Join(t.TempDir(), journalName), journalHeader{}, true); err == nil {
+		t.Fatalf("expected error when there is no journal to resume")
+	}
+}
+
+func TestJournalFinishRemovesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), journalName)
+	j, _, err := openJournal(path, journalHeader{InputPath: "/in", Fingerprint: "fp"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.finish(); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected journal to be removed, got %v", err)
+	}
+}
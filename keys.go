@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const hashKeyEnv = "GS_HASH_KEY"
+
+// hashKey is the HMAC key for hashed tokens plus the ID embedded in tokens
+// through {keyid} so outputs can be traced to the key that produced them.
+type hashKey struct {
+	secret string
+	id     string
+}
+
+// loadHashKey resolves the HMAC key from a key file, then $GS_HASH_KEY, then
+// the deprecated -hash-salt flag. A missing key yields an empty key.
+func loadHashKey(keyFile, keyID, salt string) (hashKey, error) {
+	secret := ""
+	switch {
+	case strings.TrimSpace(keyFile) != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return hashKey{}, fmt.Errorf("failed to read hash key file: %w", err)
+		}
+		secret = strings.TrimSpace(string(data))
+		if secret == "" {
+			return hashKey{}, fmt.Errorf("hash key file %s is empty", keyFile)
+		}
+	case os.Getenv(hashKeyEnv) != "":
+		secret = os.Getenv(hashKeyEnv)
+	case salt != "":
+		fmt.Fprintln(os.Stderr, "warning: -hash-salt is deprecated and visible in shell history; use -hash-key-file or $"+hashKeyEnv)
+		secret = salt
+	}
+	id := strings.TrimSpace(keyID)
+	if id == "" && secret != "" {
+		id = deriveKeyID(secret)
+	}
+	return hashKey{secret: secret, id: id}, nil
+}
+
+// deriveKeyID returns a short, non-reversible identifier for a key.
+func deriveKeyID(secret string) string {
+	sum := sha256.Sum256([]byte("gs-key-id\x00" + secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// hashMatch returns the first length hex characters of HMAC-SHA256(key, value).
+func hashMatch(value, key string, length int) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	encoded := hex.EncodeToString(mac.Sum(nil))
+	if length > len(encoded) {
+		length = len(encoded)
+	}
+	return encoded[:length]
+}
+
+// runRekey rewrites hashed tokens in existing outputs from an old key to a new
+// one. HMAC tokens cannot be reversed, so each original source is rescanned
+// with the same patterns and templates, and every token rendered under the old
+// key is swapped for the one the new key renders in its output file. With
+// -date-shift, dates shifted by the old key's offsets are re-shifted too.
+func runRekey(args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	sourcePath := fs.String("source", "", "Original (unredacted) file or directory the outputs were produced from")
+	targetPath := fs.String("target", "", "Redacted file or directory whose tokens should be rewritten")
+	extensions := fs.String("extensions", ".txt,.md,.csv", "Comma-separated list of file extensions to include for directories")
+	oldKeyFile := fs.String("old-key-file", "", "File containing the key the outputs were produced with")
+	oldKeyID := fs.String("old-key-id", "", "Key ID used in existing tokens (default: derived from the old key)")
+	newKeyFile := fs.String("new-key-file", "", "File containing the replacement key")
+	newKeyID := fs.String("new-key-id", "", "Key ID for rewritten tokens (default: derived from the new key)")
+	maskOpts := registerMaskFlags(fs)
+	patternOpts := registerPatternFlags(fs)
+	fs.Parse(args)
+
+	if *sourcePath == "" || *targetPath == "" {
+		return errors.New("rekey requires -source and -target")
+	}
+	if *oldKeyFile == "" || *newKeyFile == "" {
+		return errors.New("rekey requires -old-key-file and -new-key-file")
+	}
+	oldKey, err := loadHashKey(*oldKeyFile, *oldKeyID, "")
+	if err != nil {
+		return err
+	}
+	newKey, err := loadHashKey(*newKeyFile, *newKeyID, "")
+	if err != nil {
+		return err
+	}
+	if maskOpts.hashKeyFile != "" || maskOpts.hashSalt != "" {
+		return errors.New("rekey takes its keys from -old-key-file and -new-key-file")
+	}
+	oldCfg, err := maskOpts.buildWithKey(oldKey)
+	if err != nil {
+		return err
+	}
+	newCfg, err := maskOpts.buildWithKey(newKey)
+	if err != nil {
+		return err
+	}
+	if !oldCfg.usesHashTokens() && !oldCfg.dateShift {
+		return errors.New("rekey needs the -hash, {hash} template or -date-shift options the outputs were produced with")
+	}
+	patterns, err := patternOpts.build()
+	if err != nil {
+		return err
+	}
+
+	sourceRoot, err := filepath.Abs(*sourcePath)
+	if err != nil {
+		return err
+	}
+	targetRoot, err := filepath.Abs(*targetPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(sourceRoot)
+	if err != nil {
+		return err
+	}
+	sources, err := listFiles(sourceRoot, parseExtensions(*extensions))
+	if err != nil {
+		return fmt.Errorf("failed to collect source files: %w", err)
+	}
+	sourceIsDir := info.IsDir()
+	rewritten, files := 0, 0
+	for _, path := range sources {
+		// Outputs mirror the source tree, and date shift offsets are keyed
+		// by the same relative path redactFile uses.
+		rel, target := path, targetRoot
+		if sourceIsDir {
+			rel, _ = filepath.Rel(sourceRoot, path)
+			target = filepath.Join(targetRoot, rel)
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(target)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		mapping := rekeyMapping(string(source), rel, patterns, oldCfg, newCfg)
+		updated, count := rekeyContent(string(data), mapping)
+		if count == 0 {
+			continue
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(target, []byte(updated), outputModes{file: info.Mode().Perm(), dir: defaultOutputModes.dir}); err != nil {
+			return err
+		}
+		rewritten += count
+		files++
+	}
+	fmt.Printf("Rekeyed %d tokens in %d files (%s -> %s)\n", rewritten, files, oldKey.id, newKey.id)
+	return nil
+}
+
+// rekeyMapping maps each replacement rendered for content under the old
+// configuration to the one the new configuration renders for the same match.
+// rel is the source path date shift offsets are keyed by.
+func rekeyMapping(content, rel string, patterns []pattern, oldCfg, newCfg maskConfig) map[string]string {
+	if oldCfg.dateShift {
+		oldCfg.shiftDays = dateShiftOffset(oldCfg.shiftKey, rel, oldCfg.shiftMax)
+		newCfg.shiftDays = dateShiftOffset(newCfg.shiftKey, rel, newCfg.shiftMax)
+	}
+	oldMatches := findMatches(content, patterns, oldCfg)
+	newMatches := findMatches(content, patterns, newCfg)
+	mapping := map[string]string{}
+	for i, m := range oldMatches {
+		if i < len(newMatches) && m.replacement != newMatches[i].replacement {
+			mapping[m.replacement] = newMatches[i].replacement
+		}
+	}
+	return mapping
+}
+
+// rekeyContent swaps every whole rendered token in mapping for its new form
+// and returns how many tokens changed. Only complete tokens are touched, so a
+// key ID that also occurs as an ordinary word in the text is left alone.
+func rekeyContent(content string, mapping map[string]string) (string, int) {
+	if len(mapping) == 0 {
+		return content, 0
+	}
+	tokens := make([]string, 0, len(mapping))
+	for token := range mapping {
+		tokens = append(tokens, regexp.QuoteMeta(token))
+	}
+	sort.Slice(tokens, func(i, j int) bool { return len(tokens[i]) > len(tokens[j]) })
+	count := 0
+	tokenRe := regexp.MustCompile(strings.Join(tokens, "|"))
+	content = tokenRe.ReplaceAllStringFunc(content, func(token string) string {
+		count++
+		return mapping[token]
+	})
+	return content, count
+}
+
+func listFiles(path string, allowedExt map[string]bool) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{abs}, nil
+	}
+	return collectFiles(abs, allowedExt, map[string]bool{}, map[string]bool{})
+}
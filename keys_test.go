@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashMatchIsKeyed(t *testing.T) {
+	a := hashMatch("jane@example.com", "key-one", 8)
+	if len(a) != 8 {
+		t.Fatalf("expected 8 hex characters, got %q", a)
+	}
+	if again := hashMatch("jane@example.com", "key-one", 8); again != a {
+		t.Fatalf("expected deterministic hash, got %q and %q", a, again)
+	}
+	if other := hashMatch("jane@example.com", "key-two", 8); other == a {
+		t.Fatalf("expected different keys to produce different hashes, got %q", other)
+	}
+}
+
+func TestLoadHashKeyPrefersKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "hash.key")
+	if err := os.WriteFile(keyFile, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	t.Setenv(hashKeyEnv, "env-secret")
+
+	key, err := loadHashKey(keyFile, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.secret != "file-secret" || key.id != deriveKeyID("file-secret") {
+		t.Fatalf("expected key from file with derived id, got %+v", key)
+	}
+
+	key, err = loadHashKey("", "2026-q3", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.secret != "env-secret" || key.id != "2026-q3" {
+		t.Fatalf("expected key from env with explicit id, got %+v", key)
+	}
+
+	if _, err := loadHashKey(filepath.Join(dir, "missing.key"), "", ""); err == nil {
+		t.Fatalf("expected error for missing key file")
+	}
+}
+
+func TestRekeyContentRewritesTokens(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	oldKey := hashKey{secret: "old", id: "k1"}
+	newKey := hashKey{secret: "new", id: "k2"}
+	opts := &maskOptions{mask: "[REDACTED]", template: "[{label}:{keyid}:{hash}]", hash: true, hashLength: 8}
+	oldCfg, err := opts.buildWithKey(oldKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newCfg, err := opts.buildWithKey(newKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The key ID also appears as an ordinary word, which must survive.
+	source := "Email jane@example.com about form k1 today."
+	redacted, _ := redactContent(source, patterns, oldCfg)
+
+	mapping := rekeyMapping(source, "essay.txt", patterns, oldCfg, newCfg)
+	got, count := rekeyContent(redacted, mapping)
+	want := "Email [email:k2:" + hashMatch("jane@example.com", "new", 8) + "] about form k1 today."
+	if count != 1 || got != want {
+		t.Fatalf("expected %q with one token, got %q (%d)", want, got, count)
+	}
+	if strings.Contains(got, hashMatch("jane@example.com", "old", 8)) {
+		t.Fatalf("expected old token to be removed, got %q", got)
+	}
+}
+
+func TestRekeyReshiftsDates(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir := filepath.Join(dir, "in")
+	targetDir := filepath.Join(dir, "out")
+	mustMkdir(t, sourceDir)
+	mustMkdir(t, targetDir)
+	oldKeyFile := filepath.Join(dir, "old.key")
+	newKeyFile := filepath.Join(dir, "new.key")
+	mustWrite(t, oldKeyFile, "old-secret")
+	mustWrite(t, newKeyFile, "new-secret")
+	source := "Interview on 2024-03-15 at noon."
+	mustWrite(t, filepath.Join(sourceDir, "essay.txt"), source)
+
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	render := func(secret string) string {
+		opts := &maskOptions{mask: "[REDACTED]", hashLength: 8, dateShift: true, dateShiftMax: 365}
+		cfg, err := opts.buildWithKey(hashKey{secret: secret, id: deriveKeyID(secret)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cfg.shiftDays = dateShiftOffset(cfg.shiftKey, "essay.txt", cfg.shiftMax)
+		out, _ := redactContent(source, patterns, cfg)
+		return out
+	}
+	before, want := render("old-secret"), render("new-secret")
+	if before == want {
+		t.Fatalf("expected the two keys to shift differently, got %q", before)
+	}
+	target := filepath.Join(targetDir, "essay.txt")
+	mustWrite(t, target, before)
+
+	err = runRekey([]string{
+		"-source", sourceDir, "-target", targetDir,
+		"-old-key-file", oldKeyFile, "-new-key-file", newKeyFile,
+		"-date-shift",
+	})
+	if err != nil {
+		t.Fatalf("rekey: %v", err)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read target: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("expected %q after rekey, got %q", want, got)
+	}
+}
+
+func TestMaskOptionsRejectHashWithoutKey(t *testing.T) {
+	t.Setenv(hashKeyEnv, "")
+	for _, args := range [][]string{
+		{"-hash"},
+		{"-mask-template", "[{label}:{hash}]"},
+		{"-label-template", "email=[email:{hash}]"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := registerMaskFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("parse %v: %v", args, err)
+		}
+		if _, err := opts.build(); err == nil || !strings.Contains(err.Error(), "hash key") {
+			t.Fatalf("expected missing hash key error for %v, got %v", args, err)
+		}
+	}
+
+	t.Setenv(hashKeyEnv, "env-secret")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := registerMaskFlags(fs)
+	if err := fs.Parse([]string{"-hash"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := opts.build(); err != nil {
+		t.Fatalf("expected -hash with a key to build, got %v", err)
+	}
+}
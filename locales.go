@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// localePacks holds the country-specific patterns enabled by -locale. Labels
+// are prefixed with the locale so reports show which packs fired.
+var localePacks = map[string]func() []pattern{
+	"ca": func() []pattern {
+		return []pattern{
+			{label: "ca:sin", re: regexp.MustCompile(`\b\d{3}[ -]?\d{3}[ -]?\d{3}\b`), validate: validCanadianSIN},
+			{label: "ca:postal_code", re: regexp.MustCompile(`(?i)\b[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z][ -]?\d[ABCEGHJ-NPRSTV-Z]\d\b`)},
+		}
+	},
+	"uk": func() []pattern {
+		return []pattern{
+			{label: "uk:phone", re: regexp.MustCompile(`\+44[ .-]?\(?0?\)?[ .-]?\d{2,5}[ .-]?\d{3,4}[ .-]?\d{3,4}\b`), validate: validE164},
+			{label: "uk:nino", re: regexp.MustCompile(`(?i)\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`), validate: validUKNINO},
+			{label: "uk:postcode", re: regexp.MustCompile(`\b[A-Z]{1,2}\d[A-Z\d]? ?\d[ABD-HJLNP-UW-Z]{2}\b`)},
+		}
+	},
+	"in": func() []pattern {
+		return []pattern{
+			{label: "in:phone", re: regexp.MustCompile(`\+91[ .-]?[6-9]\d{4}[ .-]?\d{5}\b`), validate: validE164},
+			{label: "in:aadhaar", re: regexp.MustCompile(`\b[2-9]\d{3}[ -]?\d{4}[ -]?\d{4}\b`), validate: validAadhaar},
+			{label: "in:pan", re: regexp.MustCompile(`\b[A-Z]{3}[ABCFGHJLPT][A-Z]\d{4}[A-Z]\b`)},
+			{label: "in:pin_code", re: regexp.MustCompile(`(?i)\b(?:pin\s*code|pincode|pin)\s*[:#-]?\s*([1-9]\d{2}\s?\d{3})\b`), group: 1},
+		}
+	},
+	"mx": func() []pattern {
+		return []pattern{
+			{label: "mx:phone", re: regexp.MustCompile(`\+52[ .-]?(?:1[ .-]?)?\(?\d{2,3}\)?[ .-]?\d{3,4}[ .-]?\d{4}\b`), validate: validE164},
+			{label: "mx:curp", re: regexp.MustCompile(`(?i)\b[A-Z][AEIOUX][A-Z]{2}\d{6}[HMX][A-Z]{2}[B-DF-HJ-NP-TV-Z]{3}[A-Z\d]\d\b`), validate: validCURP},
+			{label: "mx:postal_code", re: regexp.MustCompile(`(?i)(?:\bC\.\s?P\.|\bCP\b|\bc[oó]digo\s+postal\b)\s*[:#]?\s*(\d{5})\b`), group: 1},
+		}
+	},
+}
+
+// internationalPatterns are included whenever any locale pack is enabled.
+func internationalPatterns() []pattern {
+	return []pattern{
+		{label: "intl:iban", re: regexp.MustCompile(`(?i)\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`), validate: validIBAN},
+		{label: "intl:phone_e164", re: regexp.MustCompile(`\+[1-9]\d{0,2}(?:[ .-]?\(?\d{1,4}\)?){2,5}\b`), validate: validE164},
+	}
+}
+
+func buildLocalePatterns(raw string) ([]pattern, error) {
+	var patterns []pattern
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		locale := strings.ToLower(strings.TrimSpace(part))
+		if locale == "" || seen[locale] {
+			continue
+		}
+		pack, ok := localePacks[locale]
+		if !ok {
+			return nil, fmt.Errorf("unknown locale %q (supported: %s)", locale, strings.Join(supportedLocales(), ", "))
+		}
+		seen[locale] = true
+		patterns = append(patterns, pack()...)
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return append(patterns, internationalPatterns()...), nil
+}
+
+func supportedLocales() []string {
+	locales := make([]string, 0, len(localePacks))
+	for locale := range localePacks {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func digitsOnly(value string) string {
+	var b strings.Builder
+	for _, ch := range value {
+		if ch >= '0' && ch <= '9' {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+func validCanadianSIN(value string) bool {
+	digits := digitsOnly(value)
+	return len(digits) == 9 && luhnChecksum(digits)
+}
+
+var invalidNINOPrefixes = map[string]bool{"BG": true, "GB": true, "NK": true, "KN": true, "TN": true, "NT": true, "ZZ": true}
+
+func validUKNINO(value string) bool {
+	compact := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	return len(compact) == 9 && !invalidNINOPrefixes[compact[:2]]
+}
+
+var (
+	verhoeffMultiplication = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffPermutation = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// validAadhaar checks the 12-digit Aadhaar number with the Verhoeff checksum.
+func validAadhaar(value string) bool {
+	digits := digitsOnly(value)
+	if len(digits) != 12 {
+		return false
+	}
+	check := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		check = verhoeffMultiplication[check][verhoeffPermutation[i%8][digit]]
+	}
+	return check == 0
+}
+
+const curpAlphabet = "0123456789ABCDEFGHIJKLMNÑOPQRSTUVWXYZ"
+
+// validCURP verifies the final CURP check digit.
+func validCURP(value string) bool {
+	upper := []rune(strings.ToUpper(value))
+	if len(upper) != 18 {
+		return false
+	}
+	alphabet := []rune(curpAlphabet)
+	sum := 0
+	for i := 0; i < 17; i++ {
+		index := -1
+		for j, ch := range alphabet {
+			if ch == upper[i] {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return false
+		}
+		sum += index * (18 - i)
+	}
+	expected := (10 - sum%10) % 10
+	return int(upper[17]-'0') == expected
+}
+
+// validIBAN applies the ISO 13616 mod-97 check.
+func validIBAN(value string) bool {
+	compact := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if len(compact) < 15 || len(compact) > 34 {
+		return false
+	}
+	rearranged := compact[4:] + compact[:4]
+	var numeric strings.Builder
+	for _, ch := range rearranged {
+		switch {
+		case ch >= '0' && ch <= '9':
+			numeric.WriteRune(ch)
+		case ch >= 'A' && ch <= 'Z':
+			fmt.Fprintf(&numeric, "%d", ch-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validE164 accepts international numbers with 8 to 15 digits.
+func validE164(value string) bool {
+	digits := digitsOnly(value)
+	return len(digits) >= 8 && len(digits) <= 15
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildLocalePatternsLabelsByCountry(t *testing.T) {
+	localePatterns, err := buildLocalePatterns("ca, uk,in,mx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	patterns := append(localePatterns, defaults...)
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "SIN 046 454 286, postal K1A 0B1, NINO AB 12 34 56 C, postcode SW1A 1AA, " +
+		"Aadhaar 2341 2341 2346, PAN ABCPE1234F, PIN 110001, CURP GODE561231HDFRRN00, C.P. 06600, " +
+		"IBAN GB82 WEST 1234 5698 7654 32, call +44 20 7946 0958 or +91 98765 43210 or +52 55 1234 5678 or +33 1 23 45 67 89."
+	redacted, counts := redactContent(content, patterns, cfg)
+	expected := map[string]int{
+		"ca:sin":          1,
+		"ca:postal_code":  1,
+		"uk:nino":         1,
+		"uk:postcode":     1,
+		"uk:phone":        1,
+		"in:aadhaar":      1,
+		"in:pan":          1,
+		"in:pin_code":     1,
+		"in:phone":        1,
+		"mx:curp":         1,
+		"mx:postal_code":  1,
+		"mx:phone":        1,
+		"intl:iban":       1,
+		"intl:phone_e164": 1,
+	}
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Fatalf("expected %d %s, got %#v\n%s", count, label, counts, redacted)
+		}
+	}
+	if counts["phone"] != 0 {
+		t.Fatalf("expected international numbers to avoid the US phone label, got %#v", counts)
+	}
+}
+
+func TestLocaleValidators(t *testing.T) {
+	if !validIBAN("DE89 3704 0044 0532 0130 00") || validIBAN("DE89 3704 0044 0532 0130 01") {
+		t.Fatalf("unexpected IBAN validation")
+	}
+	if !validAadhaar("234123412346") || validAadhaar("234123412345") {
+		t.Fatalf("unexpected Aadhaar validation")
+	}
+	if !validCanadianSIN("046 454 286") || validCanadianSIN("046 454 287") {
+		t.Fatalf("unexpected SIN validation")
+	}
+	if validUKNINO("GB123456A") {
+		t.Fatalf("expected reserved NINO prefix to be rejected")
+	}
+	if _, err := buildLocalePatterns("fr"); err == nil {
+		t.Fatalf("expected error for unsupported locale")
+	}
+}
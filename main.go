@@ -2,22 +2,25 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
+	"crypto/ed25519"
 	"database/sql"
 	"encoding/csv"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 )
 
 import _ "github.com/jackc/pgx/v5/stdlib"
@@ -37,8 +40,25 @@ func (s *stringList) Set(value string) error {
 }
 
 type pattern struct {
-	label string
-	re    *regexp.Regexp
+	label    string
+	re       *regexp.Regexp
+	group    int
+	validate func(match string) bool
+	// fits, when set, checks a candidate against the text around it.
+	fits func(content string, start, end int) bool
+	// variant names a flag that changed validate, such as -keep-private-ips,
+	// so the config fingerprint can tell the two validators apart.
+	variant string
+}
+
+// match is a single validated finding, located by byte offsets into the
+// original content.
+type match struct {
+	label       string
+	start       int
+	end         int
+	replacement string
+	validated   bool
 }
 
 type fileReport struct {
@@ -47,46 +67,150 @@ type fileReport struct {
 	Redactions map[string]int `json:"redactions"`
 	Total      int            `json:"total"`
 	Skipped    bool           `json:"skipped"`
+	Unchanged  bool           `json:"unchanged,omitempty"`
+	Findings   []finding      `json:"-"`
+	Matches    []matchDetail  `json:"matches,omitempty"`
+	Run        string         `json:"run,omitempty"`
+	Backup     string         `json:"backup,omitempty"`
 }
 
+const (
+	statusComplete   = "complete"
+	statusIncomplete = "incomplete"
+)
+
+// reportSchemaVersion is bumped whenever the JSON report shape changes.
+const reportSchemaVersion = "2"
+
 type report struct {
-	GeneratedAt string         `json:"generated_at"`
-	InputPath   string         `json:"input_path"`
-	OutputPath  string         `json:"output_path"`
-	Files       int            `json:"files"`
-	Total       int            `json:"total_redactions"`
-	ByPattern   map[string]int `json:"by_pattern"`
-	Details     []fileReport   `json:"details"`
+	SchemaVersion string               `json:"schema_version"`
+	GeneratedAt   string               `json:"generated_at"`
+	InputPath     string               `json:"input_path"`
+	OutputPath    string               `json:"output_path"`
+	Status        string               `json:"status"`
+	Failure       string               `json:"failure,omitempty"`
+	Files         int                  `json:"files"`
+	Total         int                  `json:"total_redactions"`
+	ByPattern     map[string]int       `json:"by_pattern"`
+	Details       []fileReport         `json:"details"`
+	Removed       []string             `json:"removed_outputs,omitempty"`
+	Errors        []fileError          `json:"errors,omitempty"`
+	Violations    []thresholdViolation `json:"threshold_violations,omitempty"`
+	Runs          []reportRun          `json:"runs,omitempty"`
+}
+
+// fileError records a file that could not be processed under -keep-going.
+type fileError struct {
+	Path    string `json:"path"`
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+	Run     string `json:"run,omitempty"`
+}
+
+// stageError tags a per-file failure with the step that failed (read,
+// decode, write).
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (e stageError) Error() string { return e.err.Error() }
+
+func (e stageError) Unwrap() error { return e.err }
+
+func (rep *report) fail(path string, err error) {
+	stage := "redact"
+	var tagged stageError
+	if errors.As(err, &tagged) {
+		stage = tagged.stage
+	}
+	rep.Errors = append(rep.Errors, fileError{Path: path, Stage: stage, Message: err.Error()})
+}
+
+func (rep *report) add(entry fileReport) {
+	rep.Files++
+	rep.Total += entry.Total
+	for label, count := range entry.Redactions {
+		rep.ByPattern[label] += count
+	}
+	rep.Details = append(rep.Details, entry)
+}
+
+func (rep *report) sortDetails() {
+	sort.Slice(rep.Details, func(i, j int) bool {
+		return rep.Details[i].Source < rep.Details[j].Source
+	})
 }
 
 type maskConfig struct {
 	mask       string
 	template   string
-	hashSalt   string
+	hashKey    string
+	keyID      string
 	hashLength int
 	useHash    bool
+	dateShift  bool
+	shiftKey   string
+	shiftMax   int
+	shiftDays  int
+	generalize map[string]bool
+	ageBucket  int
+	yearBucket int
+
+	labelTemplates []labelTemplate
+	formatPreserve bool
+
+	// review, when set, drops matches a reviewer chose to keep before
+	// replacements are numbered. decisionsID fingerprints its decisions.
+	review      func(content string, matches []match) []match
+	decisionsID string
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				exitWith(err.Error())
+			}
+			return
+		}
+	}
+
 	inputPath := flag.String("input", "", "File or directory to redact")
 	outputPath := flag.String("output", "", "Output directory for redacted files (default: ./redacted)")
 	extensions := flag.String("extensions", ".txt,.md,.csv", "Comma-separated list of file extensions to include when input is a directory")
-	mask := flag.String("mask", "[REDACTED]", "Text to replace redactions with")
-	maskTemplate := flag.String("mask-template", "", "Template for redactions using {label}, {n}, and {hash} placeholders")
-	hashRedactions := flag.Bool("hash", false, "Use hashed redaction tokens in the mask output")
-	hashSalt := flag.String("hash-salt", "", "Optional salt for hashed redaction tokens")
-	hashLength := flag.Int("hash-length", 8, "Length of hash fragment to include in masked output")
-	namesFile := flag.String("names-file", "", "Optional file with names to redact (one per line)")
 	reportPath := flag.String("report", "", "Optional path for JSON report (default: <output>/redaction-report.json)")
 	reportCSVPath := flag.String("report-csv", "", "Optional path for CSV report")
+	reportDetail := flag.String("report-detail", detailCounts, "JSON report detail level: counts, or matches for per-match records (hashed values only)")
+	reportHTMLPath := flag.String("report-html", "", "Optional path for a self-contained HTML review report")
+	reportDiff := flag.Bool("report-diff", false, "Add a side-by-side original vs redacted diff to each file page of -report-html")
+	reportSARIFPath := flag.String("report-sarif", "", "Optional path for SARIF 2.1.0 findings (locations only, no matched text)")
 	dbLog := flag.Bool("db-log", false, "Log run summary to PostgreSQL (requires GS_PG_* env vars)")
 	dryRun := flag.Bool("dry-run", false, "Preview redactions without writing files")
 	stdout := flag.Bool("stdout", false, "Print redacted output to stdout (single-file only)")
 	skipClean := flag.Bool("skip-clean", false, "Skip writing output files with zero redactions")
-	var customRegex stringList
-	flag.Var(&customRegex, "custom-regex", "Custom regex to redact (repeatable)")
-	var disablePatterns stringList
-	flag.Var(&disablePatterns, "disable-pattern", "Pattern label to disable (repeatable, supports '*' suffix)")
+	detailedExit := flag.Bool("detailed-exit-codes", false, "Exit 4 when redactions were made and 0 only for clean runs (2 = file errors, 3 = -fail-on threshold)")
+	var failOn stringList
+	flag.Var(&failOn, "fail-on", "Exit 3 when a count rule matches, e.g. ssn>=1, id:*>0, total>100, file:total>50 (repeatable)")
+	interactive := flag.Bool("interactive", false, "Review each undecided finding in the terminal (accept, reject, or decide for every occurrence of the value)")
+	decisionsPath := flag.String("decisions", decisionsName, "Decisions file reused as an allow/deny list; written by -interactive")
+	keepGoing := flag.Bool("keep-going", false, "Record per-file failures in the report and continue instead of aborting")
+	resume := flag.Bool("resume", false, "Continue an interrupted run from <output>/"+journalName)
+	inPlace := flag.Bool("in-place", false, "Overwrite each source with its redacted text instead of writing to -output (asks for confirmation)")
+	yes := flag.Bool("yes", false, "Confirm -in-place without prompting")
+	backupDir := flag.String("backup-dir", "", "With -in-place, write an encrypted backup of each changed source under <dir>/<timestamp>/")
+	var backupRecipients stringList
+	flag.Var(&backupRecipients, "backup-recipient", "X25519 public key (PEM) that can open -backup-dir backups (repeatable)")
+	backupPassphraseFile := flag.String("backup-passphrase-file", "", "File containing a passphrase that can open -backup-dir backups (default: $"+bundlePassphraseEnv+")")
+	bundlePath := flag.String("bundle", "", "Pack the output directory into this encrypted archive (without -output, outputs are only staged temporarily)")
+	var bundleRecipients stringList
+	flag.Var(&bundleRecipients, "bundle-recipient", "X25519 public key (PEM) that can open -bundle (repeatable)")
+	bundlePassphraseFile := flag.String("bundle-passphrase-file", "", "File containing a passphrase that can open -bundle (default: $"+bundlePassphraseEnv+")")
+	signKeyPath := flag.String("sign-key", "", "Ed25519 private key (PEM) used to sign <output>/"+integrityName)
+	incremental := flag.Bool("incremental", false, "Skip sources unchanged since the last run (tracked in <output>/"+manifestName+")")
+	patternOpts := registerPatternFlags(flag.CommandLine)
+	maskOpts := registerMaskFlags(flag.CommandLine)
+	modes := registerModeFlags(flag.CommandLine)
 	var excludeDirs stringList
 	var excludePaths stringList
 	flag.Var(&excludeDirs, "exclude-dir", "Directory name to skip (repeatable)")
@@ -115,7 +239,117 @@ func main() {
 		*dryRun = true
 		outDir = ""
 	}
-	if !*dryRun {
+	var inPlaceCfg inPlaceConfig
+	if *inPlace {
+		switch {
+		case *stdout:
+			exitWith("-in-place cannot be combined with -stdout")
+		case outDir != "":
+			exitWith("-in-place cannot be combined with -output")
+		case *incremental || *resume:
+			exitWith("-in-place cannot be combined with -incremental or -resume")
+		case *bundlePath != "" || *signKeyPath != "":
+			exitWith("-bundle and -sign-key need an output directory and cannot be combined with -in-place")
+		case *reportDiff && !*dryRun:
+			// The diff is rendered from the sources, which are already
+			// overwritten by then.
+			exitWith("-report-diff needs -dry-run when used with -in-place")
+		}
+		inPlaceCfg = inPlaceConfig{root: absInput, dryRun: *dryRun}
+		if *backupDir != "" {
+			for _, path := range backupRecipients {
+				recipient, err := loadRecipient(path)
+				if err != nil {
+					exitWith("failed to read backup recipient: " + err.Error())
+				}
+				inPlaceCfg.keys.recipients = append(inPlaceCfg.keys.recipients, recipient)
+			}
+			inPlaceCfg.keys.passphrase, err = loadBundlePassphrase(*backupPassphraseFile)
+			if err != nil {
+				exitWith(err.Error())
+			}
+			if len(inPlaceCfg.keys.recipients) == 0 && inPlaceCfg.keys.passphrase == "" {
+				exitWith("-backup-dir requires -backup-recipient, -backup-passphrase-file or $" + bundlePassphraseEnv)
+			}
+			absBackup, err := filepath.Abs(*backupDir)
+			if err != nil {
+				exitWith("failed to resolve backup path: " + err.Error())
+			}
+			if info.IsDir() && outputInsideInput(absInput, absBackup) {
+				exitWith("-backup-dir must be outside the input directory")
+			}
+			// Each run gets its own directory so a later run never replaces
+			// the backup of an earlier one.
+			inPlaceCfg.backupDir = filepath.Join(absBackup, time.Now().UTC().Format("20060102T150405Z"))
+		} else if len(backupRecipients) > 0 || *backupPassphraseFile != "" {
+			exitWith("-backup-recipient and -backup-passphrase-file require -backup-dir")
+		}
+	} else if *backupDir != "" || len(backupRecipients) > 0 || *backupPassphraseFile != "" || *yes {
+		exitWith("-backup-dir, -backup-recipient, -backup-passphrase-file and -yes require -in-place")
+	}
+	if *incremental && *dryRun {
+		exitWith("-incremental cannot be combined with -dry-run or -stdout")
+	}
+	if *reportDetail != detailCounts && *reportDetail != detailMatches {
+		exitWith(fmt.Sprintf("unknown -report-detail %q (expected %s or %s)", *reportDetail, detailCounts, detailMatches))
+	}
+	if *reportDiff && strings.TrimSpace(*reportHTMLPath) == "" {
+		exitWith("-report-diff requires -report-html")
+	}
+	var signKey ed25519.PrivateKey
+	if *signKeyPath != "" {
+		if *dryRun {
+			exitWith("-sign-key cannot be combined with -dry-run or -stdout")
+		}
+		signKey, err = loadSigningKey(*signKeyPath)
+		if err != nil {
+			exitWith("failed to read signing key: " + err.Error())
+		}
+	}
+	var bundle bundleKeys
+	staging := ""
+	if *bundlePath != "" {
+		if *dryRun {
+			exitWith("-bundle cannot be combined with -dry-run or -stdout")
+		}
+		for _, path := range bundleRecipients {
+			recipient, err := loadRecipient(path)
+			if err != nil {
+				exitWith("failed to read bundle recipient: " + err.Error())
+			}
+			bundle.recipients = append(bundle.recipients, recipient)
+		}
+		bundle.passphrase, err = loadBundlePassphrase(*bundlePassphraseFile)
+		if err != nil {
+			exitWith(err.Error())
+		}
+		if len(bundle.recipients) == 0 && bundle.passphrase == "" {
+			exitWith("-bundle requires -bundle-recipient, -bundle-passphrase-file or $" + bundlePassphraseEnv)
+		}
+		*bundlePath, err = filepath.Abs(*bundlePath)
+		if err != nil {
+			exitWith("failed to resolve bundle path: " + err.Error())
+		}
+		if outDir == "" {
+			// Without -output the plaintext tree only exists until it is
+			// packed, so there is nothing for -incremental or -resume to reuse.
+			if *incremental || *resume {
+				exitWith("-incremental and -resume require -output when used with -bundle")
+			}
+			staging, err = os.MkdirTemp("", "gs-bundle-")
+			if err != nil {
+				exitWith("failed to create staging directory: " + err.Error())
+			}
+			cleanups = append(cleanups, func() { os.RemoveAll(staging) })
+			outDir = staging
+		}
+	} else if len(bundleRecipients) > 0 || *bundlePassphraseFile != "" {
+		exitWith("-bundle-recipient and -bundle-passphrase-file require -bundle")
+	}
+	if *resume && *dryRun {
+		exitWith("-resume cannot be combined with -dry-run or -stdout")
+	}
+	if !*dryRun && !*inPlace {
 		if outDir == "" {
 			outDir = filepath.Join(".", "redacted")
 		}
@@ -123,9 +357,20 @@ func main() {
 		if err != nil {
 			exitWith("failed to resolve output path: " + err.Error())
 		}
-		if err := os.MkdirAll(outDir, 0o755); err != nil {
+		if info.IsDir() && outputInsideInput(absInput, outDir) {
+			exitWith(fmt.Sprintf("output directory %s is inside the input directory %s; choose an output directory outside it", outDir, absInput))
+		}
+		if err := os.MkdirAll(outDir, modes.dir); err != nil {
 			exitWith("failed to create output directory: " + err.Error())
 		}
+		// Checked again now that the directory exists, in case a symlink
+		// points it back into the input.
+		if info.IsDir() && outputInsideInput(absInput, outDir) {
+			exitWith(fmt.Sprintf("output directory %s resolves to a path inside the input directory %s", outDir, absInput))
+		}
+		if *bundlePath != "" && withinDir(outDir, *bundlePath) {
+			exitWith("-bundle must be written outside the output directory")
+		}
 	} else if outDir != "" {
 		outDir, err = filepath.Abs(outDir)
 		if err != nil {
@@ -133,28 +378,34 @@ func main() {
 		}
 	}
 
-	patterns, err := buildPatterns(customRegex)
+	patterns, err := patternOpts.build()
 	if err != nil {
 		exitWith(err.Error())
 	}
 
-	if *namesFile != "" {
-		names, err := loadNames(*namesFile)
-		if err != nil {
-			exitWith("failed to read names file: " + err.Error())
-		}
-		patterns = append(patterns, buildNamePatterns(names)...)
+	maskCfg, err := maskOpts.build()
+	if err != nil {
+		exitWith(err.Error())
 	}
 
-	if len(disablePatterns) > 0 {
-		patterns = filterPatterns(patterns, disablePatterns)
+	if *reportDetail == detailMatches && maskCfg.hashKey == "" {
+		exitWith("-report-detail matches needs a hash key for value_hash; set -hash-key-file or $" + hashKeyEnv)
 	}
-
-	if len(patterns) == 0 {
-		exitWith("no patterns configured")
+	if *interactive && maskCfg.hashKey == "" {
+		exitWith("-interactive needs a hash key so saved decisions are keyed; set -hash-key-file or $" + hashKeyEnv)
+	}
+	store, err := loadDecisions(*decisionsPath, hashKey{secret: maskCfg.hashKey, id: maskCfg.keyID})
+	if err != nil {
+		exitWith("failed to read decisions: " + err.Error())
+	}
+	var rev *reviewer
+	if *interactive || len(store.decisions) > 0 {
+		rev = newReviewer(store, *interactive, os.Stdin, os.Stderr)
+		maskCfg.review = rev.review
+		maskCfg.decisionsID = store.digest()
 	}
 
-	maskCfg, err := buildMaskConfig(*mask, *maskTemplate, *hashRedactions, *hashSalt, *hashLength)
+	failOnRules, err := parseFailOnRules(failOn)
 	if err != nil {
 		exitWith(err.Error())
 	}
@@ -175,7 +426,9 @@ func main() {
 	}
 
 	outputLabel := outDir
-	if *stdout {
+	if *inPlace {
+		outputLabel = "(in-place)"
+	} else if *stdout {
 		outputLabel = "(stdout)"
 	} else if *dryRun {
 		if outputLabel == "" {
@@ -183,52 +436,247 @@ func main() {
 		}
 	}
 	rep := report{
-		GeneratedAt: time.Now().Format(time.RFC3339),
-		InputPath:   absInput,
-		OutputPath:  outputLabel,
-		ByPattern:   map[string]int{},
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   time.Now().Format(time.RFC3339),
+		InputPath:     absInput,
+		OutputPath:    outputLabel,
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+	}
+
+	if *reportPath == "" {
+		if *dryRun || *inPlace {
+			*reportPath = filepath.Join(".", "redaction-report.json")
+		} else {
+			*reportPath = filepath.Join(outDir, "redaction-report.json")
+		}
+	}
+	// The bundle carries the report, and the signed manifest must point at
+	// it by a path the recipient can resolve.
+	if *bundlePath != "" && !withinDir(outDir, absOrSelf(*reportPath)) {
+		exitWith("-report must be inside the output directory when used with -bundle")
+	}
+
+	// abort writes whatever was processed as an incomplete report before
+	// exiting, so a failed run still leaves an account of its progress.
+	abort := func(message string) {
+		if staging != "" {
+			exitWith(message + "; staged outputs were discarded and no bundle was written")
+		}
+		rep.Status = statusIncomplete
+		rep.Failure = message
+		rep.sortDetails()
+		if err := writeReport(*reportPath, rep, *modes); err != nil {
+			exitWith(message + "; failed to write partial report: " + err.Error())
+		}
+		if *dryRun || *inPlace {
+			exitWith(message + "; partial report written to " + *reportPath)
+		}
+		exitWith(message + "; partial report written to " + *reportPath + ", rerun with -resume to continue")
+	}
+
+	fingerprint := configFingerprint(patterns, maskCfg, *skipClean)
+	var prevManifest, nextManifest manifest
+	manifestPath := filepath.Join(outDir, manifestName)
+	if *incremental {
+		prevManifest, err = loadManifest(manifestPath)
+		if err != nil {
+			exitWith("failed to read manifest: " + err.Error())
+		}
+		nextManifest = newManifest(fingerprint)
+	}
+
+	var progress *journal
+	journaled := map[string]manifestEntry{}
+	if !*dryRun && !*inPlace {
+		// Interactive review adds decisions as it goes, so the journal ignores
+		// them; otherwise a run interrupted mid-review could never resume.
+		journalCfg := maskCfg
+		journalCfg.decisionsID = ""
+		header := journalHeader{InputPath: absInput, Fingerprint: configFingerprint(patterns, journalCfg, *skipClean)}
+		progress, journaled, err = openJournal(filepath.Join(outDir, journalName), header, *resume)
+		if err != nil {
+			exitWith("failed to open journal: " + err.Error())
+		}
 	}
 
+	if *inPlace && !*dryRun && !*yes {
+		if !stdinIsTerminal() {
+			exitWith("-in-place overwrites sources; pass -yes to confirm when stdin is not a terminal")
+		}
+		if !confirmInPlace(os.Stdin, os.Stderr, absInput, len(files), inPlaceCfg.backupDir) {
+			exitWith("in-place redaction cancelled; no files were changed")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	stdoutContent := ""
+	previews := map[string]string{}
 	for _, path := range files {
-		entry, content, err := redactFile(path, absInput, outDir, patterns, maskCfg, *dryRun, *skipClean)
+		if ctx.Err() != nil {
+			abort("interrupted")
+		}
+		if previous, ok := journaled[path]; ok {
+			previous.Report.Findings = previous.Findings
+			if *incremental {
+				nextManifest.Files[path] = previous
+			}
+			rep.add(previous.Report)
+			continue
+		}
+		sourceHash := ""
+		if *incremental {
+			sourceHash, err = fileSHA256(path)
+			if err != nil {
+				if *keepGoing {
+					rep.fail(path, stageError{stage: "read", err: err})
+					continue
+				}
+				abort(fmt.Sprintf("failed to redact %s: %v", path, err))
+			}
+			if previous, ok := prevManifest.unchanged(path, sourceHash, fingerprint); ok {
+				nextManifest.Files[path] = previous
+				previous.Report.Unchanged = true
+				previous.Report.Findings = previous.Findings
+				rep.add(previous.Report)
+				continue
+			}
+		}
+		if rev != nil {
+			rev.file = displayPath(absInput, path)
+		}
+		var entry fileReport
+		var content string
+		if *inPlace {
+			entry, content, err = redactInPlace(path, patterns, maskCfg, inPlaceCfg)
+		} else {
+			entry, content, err = redactFile(path, absInput, outDir, patterns, maskCfg, *dryRun, *skipClean, *modes)
+		}
+		if rev != nil && rev.err != nil {
+			abort("failed to save decisions: " + rev.err.Error())
+		}
+		if err != nil && *keepGoing {
+			rep.fail(path, err)
+			continue
+		}
 		if err != nil {
-			exitWith(fmt.Sprintf("failed to redact %s: %v", path, err))
+			abort(fmt.Sprintf("failed to redact %s: %v", path, err))
 		}
 		if *stdout {
 			stdoutContent = content
 		}
-		rep.Files++
-		rep.Total += entry.Total
-		for label, count := range entry.Redactions {
-			rep.ByPattern[label] += count
+		if *dryRun && *reportHTMLPath != "" {
+			previews[path] = content
+		}
+		recorded := manifestEntry{SourceHash: sourceHash, Report: entry, Findings: entry.Findings}
+		if *incremental && !entry.Skipped {
+			recorded.OutputHash = contentSHA256([]byte(content))
 		}
-		rep.Details = append(rep.Details, entry)
+		if *incremental {
+			nextManifest.Files[path] = recorded
+		}
+		if progress != nil {
+			if err := progress.record(recorded); err != nil {
+				abort("failed to update journal: " + err.Error())
+			}
+		}
+		rep.add(entry)
 	}
 
-	sort.Slice(rep.Details, func(i, j int) bool {
-		return rep.Details[i].Source < rep.Details[j].Source
-	})
-
-	if *reportPath == "" {
-		if *dryRun {
-			*reportPath = filepath.Join(".", "redaction-report.json")
-		} else {
-			*reportPath = filepath.Join(outDir, "redaction-report.json")
+	if *incremental {
+		rep.Removed, err = removeStale(prevManifest, nextManifest, outDir)
+		if err != nil {
+			abort("failed to remove stale outputs: " + err.Error())
+		}
+		if err := writeManifest(manifestPath, nextManifest, *modes); err != nil {
+			abort("failed to write manifest: " + err.Error())
 		}
 	}
-	if err := writeReport(*reportPath, rep); err != nil {
+
+	rep.sortDetails()
+	if *reportDetail == detailMatches {
+		rep.attachMatchDetails()
+	}
+	rep.Violations = evaluateFailOn(failOnRules, rep)
+	if err := writeReport(*reportPath, rep, *modes); err != nil {
 		exitWith("failed to write report: " + err.Error())
 	}
+	if progress != nil {
+		if err := progress.finish(); err != nil {
+			exitWith("failed to remove journal: " + err.Error())
+		}
+	}
 
 	if *reportCSVPath != "" {
-		if err := writeCSVReport(*reportCSVPath, rep); err != nil {
+		if err := writeCSVReport(*reportCSVPath, rep, *modes); err != nil {
 			exitWith("failed to write CSV report: " + err.Error())
 		}
 	}
 
+	if *reportSARIFPath != "" {
+		if err := writeSARIFReport(*reportSARIFPath, rep, patternLabels(patterns), *modes); err != nil {
+			exitWith("failed to write SARIF report: " + err.Error())
+		}
+	}
+
+	if *reportHTMLPath != "" {
+		diffContext := -1
+		if *reportDiff {
+			diffContext = 3
+		}
+		if err := writeHTMLReport(*reportHTMLPath, rep, previews, diffContext, *modes); err != nil {
+			exitWith("failed to write HTML report: " + err.Error())
+		}
+	}
+
+	// A bundle carries the redacted outputs, the reports written into the
+	// output directory and the signature over them. Run metadata stays
+	// behind, and so does an HTML report with -report-diff, which holds
+	// original text.
+	var bundleFiles []string
+	if *bundlePath != "" {
+		reserved := []string{*reportPath, *reportCSVPath, *reportSARIFPath, *reportHTMLPath, manifestPath, filepath.Join(outDir, journalName), filepath.Join(outDir, integrityName)}
+		bundleFiles, err = bundledOutputs(rep, outDir, reserved)
+		if err != nil {
+			exitWith("refusing to bundle: " + err.Error())
+		}
+		reports := []string{*reportPath, *reportCSVPath, *reportSARIFPath}
+		if !*reportDiff {
+			reports = append(reports, *reportHTMLPath)
+		}
+		bundleFiles = append(bundleFiles, bundledReports(outDir, reports)...)
+	}
+
+	integrityPath := ""
+	if signKey != nil {
+		integrityPath, err = writeIntegrityManifest(outDir, *reportPath, bundleFiles, signKey, *modes)
+		if err != nil {
+			exitWith("failed to write integrity manifest: " + err.Error())
+		}
+		if bundleFiles != nil {
+			bundleFiles = append(bundleFiles, integrityPath)
+		}
+	}
+
+	bundled := 0
+	if *bundlePath != "" {
+		bundled, err = writeBundle(*bundlePath, outDir, bundleFiles, bundle)
+		if err != nil {
+			exitWith("failed to write bundle: " + err.Error())
+		}
+	}
+	// Staged files are gone once the run ends, so point at their place in
+	// the bundle instead.
+	reportLabel := *reportPath
+	if staging != "" {
+		reportLabel = stagedLabel(*bundlePath, staging, reportLabel)
+		integrityPath = stagedLabel(*bundlePath, staging, integrityPath)
+	}
+
 	if *dbLog {
-		if err := logRun(rep, *reportPath, *reportCSVPath, *dryRun); err != nil {
+		if err := logRun(rep, reportLabel, *reportCSVPath, *dryRun); err != nil {
 			exitWith("failed to log to database: " + err.Error())
 		}
 	}
@@ -236,25 +684,83 @@ func main() {
 	if *stdout {
 		fmt.Print(stdoutContent)
 	}
-	printSummary(rep, *reportPath, *stdout)
+	if *inPlace && *dryRun {
+		printInPlacePreview(os.Stdout, rep)
+	}
+	printSummary(rep, reportLabel, *stdout)
+	if integrityPath != "" {
+		fmt.Printf("Integrity manifest: %s\n", integrityPath)
+	}
+	if *bundlePath != "" {
+		fmt.Printf("Bundle: %s (%d files)\n", *bundlePath, bundled)
+	}
+	runCleanups()
+	if code := runExitCode(rep, *detailedExit); code != exitClean {
+		os.Exit(code)
+	}
+}
+
+// subcommands are dispatched on the first argument; anything else runs the
+// default redaction flow.
+var subcommands = map[string]func(args []string) error{
+	"rekey":           runRekey,
+	"diff":            runDiff,
+	"report":          runReportCommand,
+	"keygen":          runKeygen,
+	"unpack":          runUnpack,
+	"verify-manifest": runVerifyManifest,
+}
+
+// Exit codes. Fatal errors exit with 1; exitRedacted is only used with
+// -detailed-exit-codes so existing callers keep seeing 0 for a finished run.
+const (
+	exitClean      = 0
+	exitFatal      = 1
+	exitFileErrors = 2
+	exitThreshold  = 3
+	exitRedacted   = 4
+)
+
+// cleanups run before the process exits, including on fatal errors, so a
+// failed run does not leave staged plaintext behind.
+var cleanups []func()
+
+func runCleanups() {
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+	cleanups = nil
 }
 
 func exitWith(message string) {
 	fmt.Fprintln(os.Stderr, message)
-	os.Exit(1)
+	runCleanups()
+	os.Exit(exitFatal)
 }
 
 func buildPatterns(custom []string) ([]pattern, error) {
 	patterns := []pattern{
 		{label: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		{label: "imei", re: regexp.MustCompile(`(?i)\bIMEI(?:\s+(?:number|no\.?|#))?\s*[:#]?\s*(\d{15}|\d{2}[ -]\d{6}[ -]\d{6}[ -]\d)\b`), group: 1, validate: validIMEI},
 		{label: "phone", re: regexp.MustCompile(`(?i)(?:\+?1[\s.-]?)?(?:\(\s*\d{3}\s*\)|\d{3})[\s.-]?\d{3}[\s.-]?\d{4}`)},
 		{label: "ssn", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
 		{label: "dob", re: regexp.MustCompile(`\b(?:0?[1-9]|1[0-2])[/-](?:0?[1-9]|[12]\d|3[01])[/-](?:19|20)\d{2}\b`)},
-		{label: "street_address", re: regexp.MustCompile(`\b\d+\s+[A-Za-z0-9.\-\s]+\s+(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Drive|Dr|Lane|Ln|Way|Court|Ct)\b`)},
+		{label: "date", re: datePatternRe, validate: validDate, fits: outsideDottedRun},
+		{label: "age", re: regexp.MustCompile(`(?i)\b(?:i['’]?m|i am|aged?|turned|turning)\s+(\d{1,2})\b`), group: 1, validate: validAge, fits: notFollowedByUnit},
+		{label: "age", re: regexp.MustCompile(`(?i)\b(\d{1,2})[- ](?:years?|yrs?)[- ]old\b`), group: 1, validate: validAge},
+		{label: "grad_year", re: regexp.MustCompile(`(?i)\bclass\s+of\s+((?:'|’)?\d{2}|\d{4})\b`), group: 1, validate: validGradYear},
+		{label: "grad_year", re: regexp.MustCompile(`(?i)\bgraduat(?:e|es|ed|ing|ion)\s+(?:in\s+|year:?\s*|date:?\s*)?(?:(?:spring|summer|fall|winter|may|june)\s+(?:of\s+)?)?(\d{4})\b`), group: 1, validate: validGradYear},
+		{label: "street_address", re: addressPatternRe},
+	}
+	patterns = append(patterns, buildSocialPatterns()...)
+	patterns = append(patterns, []pattern{
 		{label: "url", re: regexp.MustCompile(`\bhttps?://[^\s]+`)},
-		{label: "ip_address", re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
-		{label: "credit_card", re: regexp.MustCompile(`\b(?:\d[ -]*?){13,19}\b`)},
-	}
+		{label: "ipv6_address", re: regexp.MustCompile(`(?i)(?:^|[^0-9a-z:.])((?:[0-9a-f]{0,4}:){2,7}(?:(?:\d{1,3}\.){3}\d{1,3}|[0-9a-f]{0,4})(?:%[0-9a-z]+)?)`), group: 1, validate: validIPv6Address},
+		{label: "mac_address", re: regexp.MustCompile(`(?i)\b(?:[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}|[0-9a-f]{4}\.[0-9a-f]{4}\.[0-9a-f]{4})\b`), validate: validMACAddress},
+		{label: "ip_address", re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), validate: validIPAddress},
+		{label: "credit_card", re: regexp.MustCompile(`\b(?:\d[ -]*?){13,19}\b`), validate: luhnValidToken},
+		{label: "device_serial", re: regexp.MustCompile(`(?i)\b(?:serial(?:\s+(?:number|no\.?|#))?|S/N|SN)\s*[:#]?\s*([A-Z0-9][A-Z0-9\-]{5,24})\b`), group: 1, validate: validDeviceSerial},
+	}...)
 
 	for _, raw := range custom {
 		re, err := regexp.Compile(raw)
@@ -295,13 +801,13 @@ func buildNamePatterns(names []string) []pattern {
 	return patterns
 }
 
-type disableMatcher struct {
+type labelMatcher struct {
 	exact    map[string]bool
 	prefixes []string
 }
 
-func buildDisableMatcher(values []string) disableMatcher {
-	m := disableMatcher{exact: map[string]bool{}}
+func buildLabelMatcher(values []string) labelMatcher {
+	m := labelMatcher{exact: map[string]bool{}}
 	for _, raw := range values {
 		trimmed := strings.TrimSpace(raw)
 		if trimmed == "" {
@@ -319,7 +825,7 @@ func buildDisableMatcher(values []string) disableMatcher {
 	return m
 }
 
-func (m disableMatcher) matches(label string) bool {
+func (m labelMatcher) matches(label string) bool {
 	if m.exact[label] {
 		return true
 	}
@@ -331,9 +837,13 @@ func (m disableMatcher) matches(label string) bool {
 	return false
 }
 
+func (m labelMatcher) empty() bool {
+	return len(m.exact) == 0 && len(m.prefixes) == 0
+}
+
 func filterPatterns(patterns []pattern, disabled []string) []pattern {
-	matcher := buildDisableMatcher(disabled)
-	if len(matcher.exact) == 0 && len(matcher.prefixes) == 0 {
+	matcher := buildLabelMatcher(disabled)
+	if matcher.empty() {
 		return patterns
 	}
 	var filtered []pattern
@@ -346,6 +856,35 @@ func filterPatterns(patterns []pattern, disabled []string) []pattern {
 	return filtered
 }
 
+// selectOptionalPatterns returns the opt-in patterns whose labels match the
+// enabled values. Every value must match at least one optional pattern.
+func selectOptionalPatterns(optional []pattern, enabled []string) ([]pattern, error) {
+	var selected []pattern
+	for _, raw := range enabled {
+		matcher := buildLabelMatcher([]string{raw})
+		if matcher.empty() {
+			continue
+		}
+		found := false
+		for _, pat := range optional {
+			if matcher.matches(pat.label) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown optional pattern %q", raw)
+		}
+	}
+	matcher := buildLabelMatcher(enabled)
+	for _, pat := range optional {
+		if matcher.matches(pat.label) {
+			selected = append(selected, pat)
+		}
+	}
+	return selected, nil
+}
+
 func parseExtensions(raw string) map[string]bool {
 	result := map[string]bool{}
 	for _, part := range strings.Split(raw, ",") {
@@ -428,53 +967,119 @@ func collectFiles(root string, allowedExt map[string]bool, excludeDirs map[strin
 }
 
 func redactContent(content string, patterns []pattern, maskCfg maskConfig) (string, map[string]int) {
+	matches := findMatches(content, patterns, maskCfg)
 	redactions := map[string]int{}
-	maskTemplate := strings.TrimSpace(maskCfg.template)
+	for _, m := range matches {
+		redactions[m.label]++
+	}
+	return applyMatches(content, matches), redactions
+}
+
+// findMatches runs each pattern against the original content in order. A
+// match that overlaps one claimed by an earlier pattern is dropped, so the
+// pattern order decides which label wins.
+func findMatches(content string, patterns []pattern, maskCfg maskConfig) []match {
+	var matches []match
 	for _, pat := range patterns {
-		if maskTemplate != "" {
-			counter := 0
-			content = pat.re.ReplaceAllStringFunc(content, func(match string) string {
-				if pat.label == "credit_card" && !luhnValidToken(match) {
-					return match
-				}
-				hash := ""
-				if maskCfg.useHash || strings.Contains(maskTemplate, "{hash}") {
-					hash = hashMatch(match, maskCfg.hashSalt, maskCfg.hashLength)
-				}
-				counter++
-				redactions[pat.label]++
-				return applyMaskTemplate(maskTemplate, pat.label, counter, hash)
-			})
-			continue
+		for _, loc := range pat.re.FindAllStringSubmatchIndex(content, -1) {
+			start, end := loc[0], loc[1]
+			if pat.group > 0 {
+				start, end = loc[2*pat.group], loc[2*pat.group+1]
+			}
+			if start < 0 || start == end {
+				continue
+			}
+			if pat.validate != nil && !pat.validate(content[start:end]) {
+				continue
+			}
+			if pat.fits != nil && !pat.fits(content, start, end) {
+				continue
+			}
+			if overlapsMatch(matches, start, end) {
+				continue
+			}
+			matches = append(matches, match{label: pat.label, start: start, end: end, validated: pat.validate != nil})
 		}
-		if pat.label == "credit_card" {
-			content = pat.re.ReplaceAllStringFunc(content, func(match string) string {
-				if !luhnValidToken(match) {
-					return match
-				}
-				redactions[pat.label]++
-				return maskCfg.mask
-			})
-			continue
+	}
+	if maskCfg.review != nil {
+		matches = maskCfg.review(content, matches)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	// {n} numbers distinct values in order of appearance, so a repeated name
+	// keeps its number and two people never share one.
+	numbers := map[string]map[string]int{}
+	for i, m := range matches {
+		group := maskCfg.numberingGroup(m.label)
+		if numbers[group] == nil {
+			numbers[group] = map[string]int{}
 		}
-		matches := pat.re.FindAllStringIndex(content, -1)
-		if len(matches) == 0 {
-			continue
+		value := strings.ToLower(strings.TrimSpace(content[m.start:m.end]))
+		n, ok := numbers[group][value]
+		if !ok {
+			n = len(numbers[group]) + 1
+			numbers[group][value] = n
+		}
+		matches[i].replacement = maskCfg.replacement(m.label, content[m.start:m.end], n)
+	}
+	return matches
+}
+
+func overlapsMatch(matches []match, start, end int) bool {
+	for _, m := range matches {
+		if start < m.end && m.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func applyMatches(content string, matches []match) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(content[last:m.start])
+		b.WriteString(m.replacement)
+		last = m.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+func (cfg maskConfig) replacement(label, match string, index int) string {
+	if cfg.dateShift && isDateLabel(label) {
+		if shifted, ok := shiftDate(match, cfg.shiftDays); ok {
+			return shifted
 		}
-		redactions[pat.label] += len(matches)
-		content = pat.re.ReplaceAllString(content, maskCfg.mask)
 	}
-	return content, redactions
+	if cfg.generalize[label] {
+		if bucket, ok := generalizeValue(label, match, cfg); ok {
+			return bucket
+		}
+	}
+	if cfg.formatPreserve && !cfg.hasLabelTemplate(label) {
+		return formatPreservingSurrogate(label, match, cfg.hashKey)
+	}
+	maskTemplate := cfg.templateFor(label)
+	if maskTemplate == "" {
+		return cfg.mask
+	}
+	hash := ""
+	if cfg.useHash || strings.Contains(maskTemplate, "{hash}") {
+		hash = hashMatch(match, cfg.hashKey, cfg.hashLength)
+	}
+	return renderMaskTemplate(maskTemplate, label, match, index, hash, cfg.keyID)
 }
 
-func redactFile(path, inputRoot, outputRoot string, patterns []pattern, maskCfg maskConfig, dryRun bool, skipClean bool) (fileReport, string, error) {
+func redactFile(path, inputRoot, outputRoot string, patterns []pattern, maskCfg maskConfig, dryRun bool, skipClean bool, modes outputModes) (fileReport, string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return fileReport{}, "", err
+		return fileReport{}, "", stageError{stage: "read", err: err}
+	}
+	if !utf8.Valid(data) {
+		return fileReport{}, "", stageError{stage: "decode", err: errors.New("file is not valid UTF-8")}
 	}
-
-	content := string(data)
-	redacted, redactions := redactContent(content, patterns, maskCfg)
 
 	rel := path
 	if info, err := os.Stat(inputRoot); err == nil && info.IsDir() {
@@ -483,6 +1088,18 @@ func redactFile(path, inputRoot, outputRoot string, patterns []pattern, maskCfg
 		}
 	}
 
+	if maskCfg.dateShift {
+		maskCfg.shiftDays = dateShiftOffset(maskCfg.shiftKey, rel, maskCfg.shiftMax)
+	}
+
+	content := string(data)
+	matches := findMatches(content, patterns, maskCfg)
+	redactions := map[string]int{}
+	for _, m := range matches {
+		redactions[m.label]++
+	}
+	redacted := applyMatches(content, matches)
+
 	target := ""
 	if outputRoot != "" {
 		target = filepath.Join(outputRoot, rel)
@@ -497,11 +1114,8 @@ func redactFile(path, inputRoot, outputRoot string, patterns []pattern, maskCfg
 		if skipClean && total == 0 {
 			skipped = true
 		} else {
-			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-				return fileReport{}, "", err
-			}
-			if err := os.WriteFile(target, []byte(redacted), 0o644); err != nil {
-				return fileReport{}, "", err
+			if err := writeFileAtomic(target, []byte(redacted), modes); err != nil {
+				return fileReport{}, "", stageError{stage: "write", err: err}
 			}
 		}
 	}
@@ -512,6 +1126,7 @@ func redactFile(path, inputRoot, outputRoot string, patterns []pattern, maskCfg
 		Redactions: redactions,
 		Total:      total,
 		Skipped:    skipped,
+		Findings:   locateFindings(content, matches, maskCfg.hashKey),
 	}, redacted, nil
 }
 
@@ -531,6 +1146,13 @@ func luhnValid(number string) bool {
 	if len(number) < 13 || len(number) > 19 {
 		return false
 	}
+	return luhnChecksum(number)
+}
+
+func luhnChecksum(number string) bool {
+	if number == "" {
+		return false
+	}
 	sum := 0
 	double := false
 	for i := len(number) - 1; i >= 0; i-- {
@@ -551,11 +1173,14 @@ func luhnValid(number string) bool {
 	return sum%10 == 0
 }
 
-func buildMaskConfig(mask, template string, hashEnabled bool, salt string, hashLength int) (maskConfig, error) {
+func buildMaskConfig(mask, template string, hashEnabled bool, key string, hashLength int) (maskConfig, error) {
 	template = strings.TrimSpace(template)
 	if hashLength <= 0 || hashLength > 64 {
 		return maskConfig{}, errors.New("hash-length must be between 1 and 64")
 	}
+	if err := validateMaskTemplate(template); err != nil {
+		return maskConfig{}, err
+	}
 	if hashEnabled && template != "" && !strings.Contains(template, "{hash}") {
 		return maskConfig{}, errors.New("mask-template must include {hash} when --hash is enabled")
 	}
@@ -565,57 +1190,64 @@ func buildMaskConfig(mask, template string, hashEnabled bool, salt string, hashL
 	return maskConfig{
 		mask:       mask,
 		template:   template,
-		hashSalt:   salt,
+		hashKey:    key,
 		hashLength: hashLength,
 		useHash:    hashEnabled,
 	}, nil
 }
 
-func hashMatch(value, salt string, length int) string {
-	sum := sha256.Sum256([]byte(salt + value))
-	encoded := hex.EncodeToString(sum[:])
-	if length > len(encoded) {
-		length = len(encoded)
+// enableDateShift switches dob and date matches to shifted dates. The hash key
+// keys the per-file offsets when set; otherwise a random key is used per run.
+func enableDateShift(cfg maskConfig, maxDays int) (maskConfig, error) {
+	if maxDays <= 0 {
+		return maskConfig{}, errors.New("date-shift-max-days must be greater than 0")
 	}
-	return encoded[:length]
+	key := cfg.hashKey
+	if key == "" {
+		generated, err := randomDateShiftKey()
+		if err != nil {
+			return maskConfig{}, fmt.Errorf("failed to generate date shift key: %w", err)
+		}
+		key = generated
+	}
+	cfg.dateShift = true
+	cfg.shiftKey = key
+	cfg.shiftMax = maxDays
+	return cfg, nil
 }
 
-func writeReport(path string, rep report) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
+func writeReport(path string, rep report, modes outputModes) error {
 	data, err := json.MarshalIndent(rep, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0o644)
-}
-
-func writeCSVReport(path string, rep report) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
+	if err := validateReportJSON(data); err != nil {
+		return fmt.Errorf("report does not match schema version %s: %w", reportSchemaVersion, err)
 	}
+	return writeFileAtomic(path, data, modes)
+}
 
+func writeCSVReport(path string, rep report, modes outputModes) error {
 	labels := make([]string, 0, len(rep.ByPattern))
 	for label := range rep.ByPattern {
 		labels = append(labels, label)
 	}
 	sort.Strings(labels)
 
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	return writeAtomic(path, modes.file, modes.dir, func(w io.Writer) error {
+		return writeCSVRows(w, rep, labels)
+	})
+}
 
-	writer := csv.NewWriter(file)
-	header := append([]string{"source", "target", "total_redactions", "skipped"}, labels...)
+func writeCSVRows(w io.Writer, rep report, labels []string) error {
+	writer := csv.NewWriter(w)
+	header := append([]string{"source", "target", "total_redactions", "skipped", "error"}, labels...)
 	if err := writer.Write(header); err != nil {
 		return err
 	}
 
 	for _, entry := range rep.Details {
-		row := []string{entry.Source, entry.Target, fmt.Sprintf("%d", entry.Total), fmt.Sprintf("%t", entry.Skipped)}
+		row := []string{entry.Source, entry.Target, fmt.Sprintf("%d", entry.Total), fmt.Sprintf("%t", entry.Skipped), ""}
 		for _, label := range labels {
 			row = append(row, fmt.Sprintf("%d", entry.Redactions[label]))
 		}
@@ -623,6 +1255,15 @@ func writeCSVReport(path string, rep report) error {
 			return err
 		}
 	}
+	for _, failure := range rep.Errors {
+		row := []string{failure.Path, "", "", "", failure.Stage + ": " + failure.Message}
+		for range labels {
+			row = append(row, "")
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
 	writer.Flush()
 	return writer.Error()
 }
@@ -641,15 +1282,37 @@ func printSummary(rep report, reportPath string, toStderr bool) {
 	for _, label := range labels {
 		fmt.Fprintf(out, "  %s: %d\n", label, rep.ByPattern[label])
 	}
-	skipped := 0
+	skipped, unchanged := 0, 0
 	for _, entry := range rep.Details {
 		if entry.Skipped {
 			skipped++
 		}
+		if entry.Unchanged {
+			unchanged++
+		}
 	}
 	if skipped > 0 {
 		fmt.Fprintf(out, "  skipped_clean_files: %d\n", skipped)
 	}
+	if unchanged > 0 {
+		fmt.Fprintf(out, "  unchanged_files: %d\n", unchanged)
+	}
+	if len(rep.Removed) > 0 {
+		fmt.Fprintf(out, "  removed_outputs: %d\n", len(rep.Removed))
+	}
+	if len(rep.Errors) > 0 {
+		fmt.Fprintf(out, "  failed_files: %d\n", len(rep.Errors))
+		for _, failure := range rep.Errors {
+			fmt.Fprintf(os.Stderr, "failed %s (%s): %s\n", failure.Path, failure.Stage, failure.Message)
+		}
+	}
+	for _, violation := range rep.Violations {
+		if violation.Path != "" {
+			fmt.Fprintf(os.Stderr, "fail-on %s: %s has %d\n", violation.Rule, violation.Path, violation.Count)
+		} else {
+			fmt.Fprintf(os.Stderr, "fail-on %s: run has %d\n", violation.Rule, violation.Count)
+		}
+	}
 	fmt.Fprintf(out, "Report: %s\n", reportPath)
 }
 
@@ -728,6 +1391,9 @@ func logRun(rep report, reportPath, reportCSVPath string, dryRun bool) error {
 			report_path TEXT NOT NULL,
 			report_csv_path TEXT
 		);
+		ALTER TABLE groupscholar_essay_anonymizer.run_log
+			ADD COLUMN IF NOT EXISTS error_count INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS errors JSONB;
 	`); err != nil {
 		return err
 	}
@@ -737,6 +1403,14 @@ func logRun(rep report, reportPath, reportCSVPath string, dryRun bool) error {
 		return err
 	}
 
+	var errorsJSON []byte
+	if len(rep.Errors) > 0 {
+		errorsJSON, err = json.Marshal(rep.Errors)
+		if err != nil {
+			return err
+		}
+	}
+
 	generatedAt, err := time.Parse(time.RFC3339, rep.GeneratedAt)
 	if err != nil {
 		generatedAt = time.Now()
@@ -757,8 +1431,10 @@ func logRun(rep report, reportPath, reportCSVPath string, dryRun bool) error {
 			total_redactions,
 			by_pattern,
 			report_path,
-			report_csv_path
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9);
-	`, generatedAt, rep.InputPath, rep.OutputPath, dryRun, rep.Files, rep.Total, byPattern, reportPath, csvPath)
+			report_csv_path,
+			error_count,
+			errors
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11);
+	`, generatedAt, rep.InputPath, rep.OutputPath, dryRun, rep.Files, rep.Total, byPattern, reportPath, csvPath, len(rep.Errors), errorsJSON)
 	return err
 }
@@ -107,7 +107,7 @@ func TestRedactFileDryRun(t *testing.T) {
 		t.Fatalf("mask config error: %v", err)
 	}
 
-	entry, redacted, err := redactFile(input, root, outputRoot, patterns, cfg, true, false)
+	entry, redacted, err := redactFile(input, root, outputRoot, patterns, cfg, true, false, defaultOutputModes)
 	if err != nil {
 		t.Fatalf("redactFile error: %v", err)
 	}
@@ -156,7 +156,7 @@ func TestRedactFileWithHash(t *testing.T) {
 	}
 
 	outputRoot := filepath.Join(root, "out")
-	_, _, err = redactFile(input, root, outputRoot, patterns, cfg, false, false)
+	_, _, err = redactFile(input, root, outputRoot, patterns, cfg, false, false, defaultOutputModes)
 	if err != nil {
 		t.Fatalf("redactFile error: %v", err)
 	}
@@ -213,7 +213,7 @@ func TestRedactFileSkipClean(t *testing.T) {
 		t.Fatalf("mask config error: %v", err)
 	}
 
-	entry, _, err := redactFile(input, root, outputRoot, patterns, cfg, false, true)
+	entry, _, err := redactFile(input, root, outputRoot, patterns, cfg, false, true, defaultOutputModes)
 	if err != nil {
 		t.Fatalf("redactFile error: %v", err)
 	}
@@ -225,6 +225,54 @@ func TestRedactFileSkipClean(t *testing.T) {
 	}
 }
 
+func TestRedactFileRejectsInvalidUTF8(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "latin1.txt")
+	mustWrite(t, input, "Jos\xe9 lives here.")
+
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	_, _, err = redactFile(input, root, filepath.Join(root, "out"), patterns, cfg, false, false, defaultOutputModes)
+	if err == nil {
+		t.Fatalf("expected error for non-UTF-8 input")
+	}
+	rep := report{ByPattern: map[string]int{}}
+	rep.fail(input, err)
+	rep.fail(filepath.Join(root, "missing.txt"), stageError{stage: "read", err: os.ErrNotExist})
+	if len(rep.Errors) != 2 || rep.Errors[0].Stage != "decode" || rep.Errors[1].Stage != "read" {
+		t.Fatalf("expected decode and read failures, got %+v", rep.Errors)
+	}
+}
+
+func TestWriteCSVReportIncludesErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	rep := report{
+		ByPattern: map[string]int{"email": 1},
+		Details:   []fileReport{{Source: "a.txt", Target: "out/a.txt", Redactions: map[string]int{"email": 1}, Total: 1}},
+		Errors:    []fileError{{Path: "b.txt", Stage: "decode", Message: "file is not valid UTF-8"}},
+	}
+	if err := writeCSVReport(path, rep, defaultOutputModes); err != nil {
+		t.Fatalf("writeCSVReport error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	want := "source,target,total_redactions,skipped,error,email\n" +
+		"a.txt,out/a.txt,1,false,,1\n" +
+		"b.txt,,,,decode: file is not valid UTF-8,\n"
+	if string(data) != want {
+		t.Fatalf("unexpected CSV:\n%s", data)
+	}
+}
+
 func mustWrite(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
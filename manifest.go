@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	manifestName    = "redaction-manifest.json"
+	manifestVersion = 1
+)
+
+// manifest records what an incremental run produced so the next run can skip
+// sources whose content, configuration and output are all unchanged.
+type manifest struct {
+	Version     int                      `json:"version"`
+	GeneratedAt string                   `json:"generated_at"`
+	Fingerprint string                   `json:"fingerprint"`
+	Files       map[string]manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	SourceHash string     `json:"source_sha256,omitempty"`
+	OutputHash string     `json:"output_sha256,omitempty"`
+	Report     fileReport `json:"report"`
+	Findings   []finding  `json:"findings,omitempty"`
+}
+
+func newManifest(fingerprint string) manifest {
+	return manifest{
+		Version:     manifestVersion,
+		Fingerprint: fingerprint,
+		Files:       map[string]manifestEntry{},
+	}
+}
+
+// configFingerprint hashes everything that changes the bytes written for a
+// given source: the ordered pattern set, including validator variants such as
+// -keep-private-ips, and the mask configuration. The hash key and the date
+// shift key only contribute their derived IDs so the manifest never carries
+// secret material. Without a hash key the shift key is random per run, so
+// -date-shift then reprocesses every file.
+func configFingerprint(patterns []pattern, cfg maskConfig, skipClean bool) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "manifest-v%d\n", manifestVersion)
+	for _, p := range patterns {
+		fmt.Fprintf(h, "pattern %q %q %d %t %t %q\n", p.label, p.re.String(), p.group, p.validate != nil, p.fits != nil, p.variant)
+	}
+	keyID := ""
+	if cfg.hashKey != "" {
+		keyID = deriveKeyID(cfg.hashKey)
+	}
+	fmt.Fprintf(h, "mask %q %q %q %q %d %t\n", cfg.mask, cfg.template, keyID, cfg.keyID, cfg.hashLength, cfg.useHash)
+	shiftKeyID := ""
+	if cfg.shiftKey != "" {
+		shiftKeyID = deriveKeyID(cfg.shiftKey)
+	}
+	fmt.Fprintf(h, "shift %t %q %d\n", cfg.dateShift, shiftKeyID, cfg.shiftMax)
+	fmt.Fprintf(h, "generalize %v %d %d\n", cfg.generalize, cfg.ageBucket, cfg.yearBucket)
+	for _, entry := range cfg.labelTemplates {
+		fmt.Fprintf(h, "label-template %q %t %q\n", entry.label, entry.prefix, entry.template)
+	}
+	fmt.Fprintf(h, "format-preserve %t\nskip-clean %t\n", cfg.formatPreserve, skipClean)
+	fmt.Fprintf(h, "decisions %q\n", cfg.decisionsID)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadManifest reads a manifest, returning an empty one when none exists yet.
+func loadManifest(path string) (manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newManifest(""), nil
+	}
+	if err != nil {
+		return manifest{}, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]manifestEntry{}
+	}
+	return m, nil
+}
+
+func writeManifest(path string, m manifest, modes outputModes) error {
+	m.GeneratedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, modes)
+}
+
+// unchanged returns the previous report for source when the configuration
+// fingerprint and source hash match and the output on disk is still the one
+// the manifest recorded.
+func (m manifest) unchanged(source, sourceHash, fingerprint string) (manifestEntry, bool) {
+	if m.Fingerprint != fingerprint {
+		return manifestEntry{}, false
+	}
+	entry, ok := m.Files[source]
+	if !ok || entry.SourceHash != sourceHash {
+		return manifestEntry{}, false
+	}
+	if entry.Report.Skipped {
+		return entry, true
+	}
+	outputHash, err := fileSHA256(entry.Report.Target)
+	if err != nil || outputHash != entry.OutputHash {
+		return manifestEntry{}, false
+	}
+	return entry, true
+}
+
+// removeStale deletes outputs recorded in prev whose sources no longer exist
+// and returns the removed targets. Targets outside outputRoot are left alone.
+func removeStale(prev, next manifest, outputRoot string) ([]string, error) {
+	var removed []string
+	for source, entry := range prev.Files {
+		if _, ok := next.Files[source]; ok {
+			continue
+		}
+		if _, err := os.Stat(source); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		target := entry.Report.Target
+		if target == "" || entry.Report.Skipped || !withinDir(outputRoot, target) {
+			continue
+		}
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, target)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return contentSHA256(data), nil
+}
+
+func contentSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFingerprintTracksConfig(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	base := configFingerprint(patterns, cfg, false)
+	if again := configFingerprint(patterns, cfg, false); again != base {
+		t.Fatalf("expected stable fingerprint, got %q and %q", base, again)
+	}
+	if got := configFingerprint(patterns[1:], cfg, false); got == base {
+		t.Fatalf("expected fingerprint to change with the pattern set")
+	}
+	shifted, err := enableDateShift(cfg, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := configFingerprint(patterns, shifted, false)
+	shifted.shiftKey = "another-key"
+	if got := configFingerprint(patterns, shifted, false); got == first || got == base {
+		t.Fatalf("expected fingerprint to change with the date shift key")
+	}
+	cfg.mask = "[X]"
+	if got := configFingerprint(patterns, cfg, false); got == base {
+		t.Fatalf("expected fingerprint to change with the mask")
+	}
+}
+
+func TestKeepPrivateIPsInvalidatesManifest(t *testing.T) {
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fingerprint := func(args ...string) string {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts := registerPatternFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("parse %v: %v", args, err)
+		}
+		patterns, err := opts.build()
+		if err != nil {
+			t.Fatalf("build %v: %v", args, err)
+		}
+		return configFingerprint(patterns, cfg, false)
+	}
+
+	m := newManifest(fingerprint())
+	m.Files["/in/essay.txt"] = manifestEntry{SourceHash: "src", Report: fileReport{Source: "/in/essay.txt", Skipped: true}}
+	if _, ok := m.unchanged("/in/essay.txt", "src", fingerprint()); !ok {
+		t.Fatalf("expected the same flags to reuse the manifest")
+	}
+	if _, ok := m.unchanged("/in/essay.txt", "src", fingerprint("-keep-private-ips")); ok {
+		t.Fatalf("expected -keep-private-ips to invalidate the manifest")
+	}
+}
+
+func TestManifestUnchangedChecksOutput(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "essay.txt")
+	if err := os.WriteFile(target, []byte("Email [REDACTED]"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	m := newManifest("fp")
+	m.Files["/in/essay.txt"] = manifestEntry{
+		SourceHash: "src",
+		OutputHash: contentSHA256([]byte("Email [REDACTED]")),
+		Report:     fileReport{Source: "/in/essay.txt", Target: target, Total: 1},
+	}
+
+	if _, ok := m.unchanged("/in/essay.txt", "src", "fp"); !ok {
+		t.Fatalf("expected unchanged source to be skipped")
+	}
+	if _, ok := m.unchanged("/in/essay.txt", "edited", "fp"); ok {
+		t.Fatalf("expected edited source to be reprocessed")
+	}
+	if _, ok := m.unchanged("/in/essay.txt", "src", "other"); ok {
+		t.Fatalf("expected config change to reprocess")
+	}
+	if err := os.WriteFile(target, []byte("tampered"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, ok := m.unchanged("/in/essay.txt", "src", "fp"); ok {
+		t.Fatalf("expected modified output to be reprocessed")
+	}
+}
+
+func TestRemoveStaleDeletesOrphanedOutputs(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	kept := filepath.Join(dir, "kept.txt")
+	if err := os.WriteFile(kept, []byte("still here"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	orphan := filepath.Join(outDir, "gone.txt")
+	keptOut := filepath.Join(outDir, "kept.txt")
+	for _, path := range []string{orphan, keptOut} {
+		if err := os.WriteFile(path, []byte("[REDACTED]"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	prev := newManifest("fp")
+	prev.Files[filepath.Join(dir, "gone.txt")] = manifestEntry{Report: fileReport{Target: orphan}}
+	prev.Files[kept] = manifestEntry{Report: fileReport{Target: keptOut}}
+
+	removed, err := removeStale(prev, newManifest("fp"), outDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != orphan {
+		t.Fatalf("expected only the orphaned output to be removed, got %v", removed)
+	}
+	if _, err := os.Stat(keptOut); err != nil {
+		t.Fatalf("expected output with an existing source to remain: %v", err)
+	}
+}
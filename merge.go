@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// reportRun is the provenance of one report folded into a merged report.
+// Merged details and errors point back to it through their run field.
+type reportRun struct {
+	ID          string `json:"id"`
+	Report      string `json:"report"`
+	GeneratedAt string `json:"generated_at"`
+	InputPath   string `json:"input_path"`
+	OutputPath  string `json:"output_path"`
+	Status      string `json:"status"`
+}
+
+// runReportCommand dispatches "report <subcommand>".
+func runReportCommand(args []string) error {
+	if len(args) == 0 || args[0] != "merge" {
+		return errors.New("usage: report merge -output <merged.json> <report.json>...")
+	}
+	return runReportMerge(args[1:])
+}
+
+func runReportMerge(args []string) error {
+	fs := flag.NewFlagSet("report merge", flag.ExitOnError)
+	outputPath := fs.String("output", "", "Path for the merged JSON report")
+	fs.Parse(args)
+
+	if *outputPath == "" || fs.NArg() < 2 {
+		return errors.New("report merge requires -output and at least two reports")
+	}
+	var reports []report
+	for _, path := range fs.Args() {
+		rep, err := readReport(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, rep)
+	}
+	merged := mergeReports(reports, fs.Args())
+	if err := writeReport(*outputPath, merged, defaultOutputModes); err != nil {
+		return fmt.Errorf("failed to write merged report: %w", err)
+	}
+	fmt.Printf("Merged %d reports: %d files, %d redactions -> %s\n", len(reports), merged.Files, merged.Total, *outputPath)
+	return nil
+}
+
+// readReport loads a report and checks it against the schema first, so
+// reports from an incompatible version are rejected rather than misread.
+// Version 1 reports, and older ones without a schema_version, are read as
+// version 2, which only added the optional per-file backup field.
+func readReport(path string) (report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report{}, err
+	}
+	data, err = upgradeReportJSON(data)
+	if err != nil {
+		return report{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateReportJSON(data); err != nil {
+		return report{}, fmt.Errorf("%s does not match report schema version %s: %w", path, reportSchemaVersion, err)
+	}
+	var rep report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return report{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return rep, nil
+}
+
+// mergeReports combines reports in argument order. When several reports
+// cover the same source, the entry from the most recently generated report
+// wins (later arguments break ties). Totals are recomputed from the kept
+// entries; threshold violations are per run and are not carried over.
+func mergeReports(reports []report, paths []string) report {
+	merged := report{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   time.Now().Format(time.RFC3339),
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+	}
+
+	type chosen struct {
+		entry fileReport
+		at    time.Time
+	}
+	bySource := map[string]chosen{}
+	var order []string
+	removedSeen := map[string]bool{}
+	for i, rep := range reports {
+		run := reportRun{
+			ID:          fmt.Sprintf("run-%d", i+1),
+			Report:      absOrSelf(paths[i]),
+			GeneratedAt: rep.GeneratedAt,
+			InputPath:   rep.InputPath,
+			OutputPath:  rep.OutputPath,
+			Status:      rep.Status,
+		}
+		merged.Runs = append(merged.Runs, run)
+		if rep.Status != statusComplete {
+			merged.Status = statusIncomplete
+		}
+		if i == 0 {
+			merged.InputPath, merged.OutputPath = rep.InputPath, rep.OutputPath
+		}
+		if merged.InputPath != rep.InputPath {
+			merged.InputPath = "(merged)"
+		}
+		if merged.OutputPath != rep.OutputPath {
+			merged.OutputPath = "(merged)"
+		}
+
+		at, _ := time.Parse(time.RFC3339, rep.GeneratedAt)
+		for _, entry := range rep.Details {
+			entry.Run = run.ID
+			previous, seen := bySource[entry.Source]
+			if !seen {
+				order = append(order, entry.Source)
+			}
+			if !seen || !at.Before(previous.at) {
+				bySource[entry.Source] = chosen{entry: entry, at: at}
+			}
+		}
+		for _, removed := range rep.Removed {
+			if !removedSeen[removed] {
+				removedSeen[removed] = true
+				merged.Removed = append(merged.Removed, removed)
+			}
+		}
+	}
+	sort.Strings(merged.Removed)
+
+	for _, source := range order {
+		merged.add(bySource[source].entry)
+	}
+	merged.sortDetails()
+
+	failed := map[string]bool{}
+	for i, rep := range reports {
+		for _, failure := range rep.Errors {
+			if _, ok := bySource[failure.Path]; ok || failed[failure.Path] {
+				continue
+			}
+			failed[failure.Path] = true
+			failure.Run = merged.Runs[i].ID
+			merged.Errors = append(merged.Errors, failure)
+		}
+	}
+	return merged
+}
+
+// reportV1Defaults fill the fields a version 1 report may lack. Reports
+// written before schema_version existed are read as version 1; they were only
+// written by finished runs and had no status field.
+var reportV1Defaults = map[string]string{
+	"generated_at":     `""`,
+	"input_path":       `""`,
+	"output_path":      `""`,
+	"status":           `"` + statusComplete + `"`,
+	"files":            `0`,
+	"total_redactions": `0`,
+	"by_pattern":       `{}`,
+	"details":          `[]`,
+}
+
+func upgradeReportJSON(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if version, ok := fields["schema_version"]; ok && string(version) != `"1"` {
+		return data, nil
+	}
+	fields["schema_version"] = json.RawMessage(`"` + reportSchemaVersion + `"`)
+	for name, value := range reportV1Defaults {
+		if _, ok := fields[name]; !ok {
+			fields[name] = json.RawMessage(value)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+func absOrSelf(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeReportsDeduplicatesAndRecomputes(t *testing.T) {
+	older := report{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   "2026-10-01T00:00:00Z",
+		InputPath:     "/batch-a",
+		OutputPath:    "/out",
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+		Errors:        []fileError{{Path: "/essays/c.txt", Stage: "read", Message: "denied"}},
+		Removed:       []string{"/out/old.txt", "/out/gone.txt"},
+	}
+	older.add(fileReport{Source: "/essays/a.txt", Redactions: map[string]int{"email": 3}, Total: 3})
+	older.add(fileReport{Source: "/essays/b.txt", Redactions: map[string]int{"ssn": 1}, Total: 1})
+	newer := report{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   "2026-10-02T00:00:00Z",
+		InputPath:     "/batch-b",
+		OutputPath:    "/out",
+		Status:        statusIncomplete,
+		ByPattern:     map[string]int{},
+		Removed:       []string{"/out/gone.txt"},
+	}
+	newer.add(fileReport{Source: "/essays/a.txt", Redactions: map[string]int{"email": 1}, Total: 1})
+	newer.add(fileReport{Source: "/essays/c.txt", Redactions: map[string]int{"phone": 2}, Total: 2})
+
+	// The newer report is passed first; it still wins for a.txt.
+	merged := mergeReports([]report{newer, older}, []string{"b.json", "a.json"})
+	if merged.Files != 3 || merged.Total != 4 || merged.ByPattern["email"] != 1 || merged.ByPattern["ssn"] != 1 || merged.ByPattern["phone"] != 2 {
+		t.Fatalf("unexpected totals: files=%d total=%d by=%v", merged.Files, merged.Total, merged.ByPattern)
+	}
+	if merged.Details[0].Source != "/essays/a.txt" || merged.Details[0].Run != "run-1" || merged.Details[1].Run != "run-2" {
+		t.Fatalf("unexpected provenance: %+v", merged.Details)
+	}
+	if len(merged.Runs) != 2 || merged.Runs[1].Report != mustAbs(t, "a.json") || merged.Runs[1].InputPath != "/batch-a" {
+		t.Fatalf("unexpected runs: %+v", merged.Runs)
+	}
+	if merged.Status != statusIncomplete || merged.InputPath != "(merged)" || merged.OutputPath != "/out" {
+		t.Fatalf("unexpected merged header: %+v", merged)
+	}
+	if len(merged.Removed) != 2 || merged.Removed[0] != "/out/gone.txt" || merged.Removed[1] != "/out/old.txt" {
+		t.Fatalf("expected removed outputs deduplicated and sorted, got %v", merged.Removed)
+	}
+	if len(merged.Errors) != 0 {
+		t.Fatalf("expected the error for a file redacted in another run to be dropped, got %+v", merged.Errors)
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatalf("abs error: %v", err)
+	}
+	return abs
+}
+
+func TestUpgradeReportJSONAcceptsVersionOne(t *testing.T) {
+	v1 := `{"schema_version":"1","generated_at":"","input_path":"","output_path":"","status":"complete","files":0,"total_redactions":0,"by_pattern":{},"details":[]}`
+	upgraded, err := upgradeReportJSON([]byte(v1))
+	if err != nil {
+		t.Fatalf("upgrade error: %v", err)
+	}
+	if err := validateReportJSON(upgraded); err != nil {
+		t.Fatalf("expected an upgraded version 1 report to validate, got %v", err)
+	}
+}
+
+func TestReadReportUpgradesUnversionedReport(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.json")
+	mustWrite(t, legacy, `{
+  "generated_at": "2026-09-01T00:00:00Z",
+  "input_path": "/batch-a",
+  "output_path": "/out",
+  "files": 1,
+  "total_redactions": 2,
+  "by_pattern": {"email": 2},
+  "details": [{"source": "/essays/a.txt", "target": "/out/a.txt", "redactions": {"email": 2}, "total": 2, "skipped": false}]
+}`)
+	current := report{
+		SchemaVersion: reportSchemaVersion,
+		GeneratedAt:   "2026-10-01T00:00:00Z",
+		InputPath:     "/batch-b",
+		OutputPath:    "/out",
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+	}
+	current.add(fileReport{Source: "/essays/b.txt", Redactions: map[string]int{"ssn": 1}, Total: 1})
+	currentPath := filepath.Join(dir, "current.json")
+	if err := writeReport(currentPath, current, defaultOutputModes); err != nil {
+		t.Fatalf("write report: %v", err)
+	}
+
+	var reports []report
+	for _, path := range []string{legacy, currentPath} {
+		rep, err := readReport(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		reports = append(reports, rep)
+	}
+	if reports[0].SchemaVersion != reportSchemaVersion || reports[0].Status != statusComplete {
+		t.Fatalf("expected legacy report to be upgraded, got version %q status %q", reports[0].SchemaVersion, reports[0].Status)
+	}
+	merged := mergeReports(reports, []string{legacy, currentPath})
+	if merged.Status != statusComplete || merged.Files != 2 || merged.Total != 3 || merged.ByPattern["email"] != 2 {
+		t.Fatalf("unexpected merged report: %+v", merged)
+	}
+}
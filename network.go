@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// validIPAddress accepts IPv4 and IPv6 literals that parse cleanly, which
+// rules out version strings like 999.1.2.3 and clock times like 10:30:00.
+func validIPAddress(value string) bool {
+	_, ok := parseIPAddress(value)
+	return ok
+}
+
+func parseIPAddress(value string) (netip.Addr, bool) {
+	if zone := strings.IndexByte(value, '%'); zone >= 0 {
+		value = value[:zone]
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+// nonIdentifyingAddress reports whether addr is in a private or reserved
+// range (RFC 1918, loopback, link-local, unique local, unspecified) that
+// says nothing about the applicant.
+func nonIdentifyingAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsUnspecified()
+}
+
+// keepPrivateAddresses leaves private and reserved IP addresses unredacted by
+// extending the validators of the ip_address and ipv6_address patterns.
+func keepPrivateAddresses(patterns []pattern) []pattern {
+	kept := make([]pattern, 0, len(patterns))
+	for _, pat := range patterns {
+		if pat.label == "ip_address" || pat.label == "ipv6_address" {
+			validate := pat.validate
+			pat.validate = func(value string) bool {
+				if validate != nil && !validate(value) {
+					return false
+				}
+				addr, ok := parseIPAddress(value)
+				return ok && !nonIdentifyingAddress(addr)
+			}
+			pat.variant = "keep-private-ips"
+		}
+		kept = append(kept, pat)
+	}
+	return kept
+}
+
+func validIPv6Address(value string) bool {
+	addr, ok := parseIPAddress(value)
+	return ok && addr.Is6()
+}
+
+// validMACAddress requires a single separator style across the address.
+func validMACAddress(value string) bool {
+	if strings.Count(value, ".") == 2 {
+		return true
+	}
+	return strings.Count(value, ":") == 5 || strings.Count(value, "-") == 5
+}
+
+func validIMEI(value string) bool {
+	digits := digitsOnly(value)
+	return len(digits) == 15 && luhnChecksum(digits)
+}
+
+func validDeviceSerial(value string) bool {
+	return strings.ContainsAny(value, "0123456789")
+}
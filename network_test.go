@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRedactContentNetworkAndDeviceIdentifiers(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "peer 2001:db8:85a3::8a2e:370:7334 via fe80::1%eth0 and ::ffff:203.0.113.9, " +
+		"mac 00:1A:2B:3C:4D:5E, IMEI: 490154203237518, serial number: C02XK1ABJG5H, " +
+		"at 10:30:00 on v999.1.2.3"
+	redacted, counts := redactContent(content, patterns, cfg)
+	expected := map[string]int{
+		"ipv6_address":  3,
+		"mac_address":   1,
+		"imei":          1,
+		"device_serial": 1,
+	}
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Fatalf("expected %d %s, got %#v\n%s", count, label, counts, redacted)
+		}
+	}
+	if counts["ip_address"] != 0 || counts["credit_card"] != 0 {
+		t.Fatalf("unexpected ip/credit card matches: %#v\n%s", counts, redacted)
+	}
+	want := "peer [REDACTED] via [REDACTED] and [REDACTED], mac [REDACTED], IMEI: [REDACTED], serial number: [REDACTED], at 10:30:00 on v999.1.2.3"
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+}
+
+func TestKeepPrivateAddresses(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	content := "router 192.168.1.1, lab 10.0.0.7, local ::1, public 203.0.113.9 and 2001:db8::1"
+	redacted, counts := redactContent(content, keepPrivateAddresses(patterns), cfg)
+	if redacted != "router 192.168.1.1, lab 10.0.0.7, local ::1, public [REDACTED] and [REDACTED]" {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+	if counts["ip_address"] != 1 || counts["ipv6_address"] != 1 {
+		t.Fatalf("unexpected counts: %#v", counts)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// patternOptions holds the flags that decide which patterns run. They are
+// shared by the redaction run and subcommands that need to find the same
+// matches (for example rekey).
+type patternOptions struct {
+	customRegex       stringList
+	disablePatterns   stringList
+	enablePatterns    stringList
+	locales           string
+	namesFile         string
+	schoolsFile       string
+	cityMinPopulation int
+	keepPrivateIPs    bool
+}
+
+func registerPatternFlags(fs *flag.FlagSet) *patternOptions {
+	o := &patternOptions{}
+	fs.StringVar(&o.namesFile, "names-file", "", "Optional file with names to redact (one per line)")
+	fs.StringVar(&o.schoolsFile, "schools-file", "", "Optional file with school names to redact (one per line)")
+	fs.IntVar(&o.cityMinPopulation, "city-min-population", 100000, "Minimum population for gazetteer cities to be redacted")
+	fs.BoolVar(&o.keepPrivateIPs, "keep-private-ips", false, "Leave private and reserved IP addresses (10.x, 192.168.x, ::1, ...) unredacted")
+	fs.StringVar(&o.locales, "locale", "", "Comma-separated international pattern packs to enable (ca,uk,in,mx)")
+	fs.Var(&o.customRegex, "custom-regex", "Custom regex to redact (repeatable)")
+	fs.Var(&o.disablePatterns, "disable-pattern", "Pattern label to disable (repeatable, supports '*' suffix)")
+	fs.Var(&o.enablePatterns, "enable-pattern", "Opt-in pattern label to enable, e.g. id:itin or id:* (repeatable, supports '*' suffix)")
+	return o
+}
+
+func (o *patternOptions) build() ([]pattern, error) {
+	patterns, err := buildPatterns(o.customRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(o.locales) != "" {
+		localePatterns, err := buildLocalePatterns(o.locales)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(localePatterns, patterns...)
+	}
+
+	if len(o.enablePatterns) > 0 {
+		optional, err := selectOptionalPatterns(optionalPatterns(), o.enablePatterns)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(optional, patterns...)
+	}
+
+	var schools []string
+	if o.schoolsFile != "" {
+		schools, err = loadNames(o.schoolsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schools file: %w", err)
+		}
+	}
+	patterns = append(patterns, buildLocationPatterns(o.cityMinPopulation, schools)...)
+
+	if o.namesFile != "" {
+		names, err := loadNames(o.namesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read names file: %w", err)
+		}
+		patterns = append(patterns, buildNamePatterns(names)...)
+	}
+
+	if len(o.disablePatterns) > 0 {
+		patterns = filterPatterns(patterns, o.disablePatterns)
+	}
+
+	if o.keepPrivateIPs {
+		patterns = keepPrivateAddresses(patterns)
+	}
+
+	if len(patterns) == 0 {
+		return nil, errors.New("no patterns configured")
+	}
+	return patterns, nil
+}
+
+// maskOptions holds the flags that decide what each match is replaced with.
+type maskOptions struct {
+	mask           string
+	template       string
+	hash           bool
+	hashSalt       string
+	hashKeyFile    string
+	hashKeyID      string
+	hashLength     int
+	labelTemplates stringList
+	formatPreserve bool
+	dateShift      bool
+	dateShiftMax   int
+	generalize     stringList
+	ageBucket      int
+	yearBucket     int
+}
+
+func registerMaskFlags(fs *flag.FlagSet) *maskOptions {
+	o := &maskOptions{}
+	fs.StringVar(&o.mask, "mask", "[REDACTED]", "Text to replace redactions with")
+	fs.StringVar(&o.template, "mask-template", "", "Template for redactions using {label}, {n}, {hash}, {keyid}, {platform}, {last4}, {len}, and {initials} placeholders")
+	fs.BoolVar(&o.hash, "hash", false, "Use hashed redaction tokens in the mask output")
+	fs.StringVar(&o.hashKeyFile, "hash-key-file", "", "File containing the HMAC key for hashed tokens (default: $"+hashKeyEnv+")")
+	fs.StringVar(&o.hashKeyID, "hash-key-id", "", "Key ID for the {keyid} placeholder (default: derived from the key)")
+	fs.StringVar(&o.hashSalt, "hash-salt", "", "Deprecated: inline HMAC key for hashed tokens; prefer -hash-key-file or $"+hashKeyEnv)
+	fs.IntVar(&o.hashLength, "hash-length", 8, "Length of hash fragment to include in masked output")
+	fs.Var(&o.labelTemplates, "label-template", "Per-label mask template as label=template (repeatable, label supports '*' suffix)")
+	fs.BoolVar(&o.formatPreserve, "format-preserve", false, "Replace digits with digits and letters with letters, keeping separators and length (deterministic with a hash key)")
+	fs.BoolVar(&o.dateShift, "date-shift", false, "Shift detected dates by a consistent per-file offset instead of masking them")
+	fs.IntVar(&o.dateShiftMax, "date-shift-max-days", 365, "Maximum number of days a date can be shifted in -date-shift mode")
+	fs.Var(&o.generalize, "generalize", "Pattern label to generalize into buckets instead of masking (repeatable or comma-separated)")
+	fs.IntVar(&o.ageBucket, "age-bucket", 3, "Bucket width in years for generalized ages")
+	fs.IntVar(&o.yearBucket, "year-bucket", 0, "Bucket width in years for generalized graduation years (0 = early/mid/late decade)")
+	return o
+}
+
+func (o *maskOptions) build() (maskConfig, error) {
+	key, err := loadHashKey(o.hashKeyFile, o.hashKeyID, o.hashSalt)
+	if err != nil {
+		return maskConfig{}, err
+	}
+	return o.buildWithKey(key)
+}
+
+// buildWithKey builds the mask configuration around an already loaded key, so
+// rekey can render the same options under both its old and new key.
+func (o *maskOptions) buildWithKey(key hashKey) (maskConfig, error) {
+	cfg, err := buildMaskConfig(o.mask, o.template, o.hash, key.secret, o.hashLength)
+	if err != nil {
+		return maskConfig{}, err
+	}
+	cfg.keyID = key.id
+	if len(o.labelTemplates) > 0 {
+		cfg, err = applyLabelTemplates(cfg, o.labelTemplates)
+		if err != nil {
+			return maskConfig{}, err
+		}
+	}
+	if key.secret == "" && cfg.usesHashTokens() {
+		return maskConfig{}, fmt.Errorf("-hash and {hash} templates need a hash key; set -hash-key-file or $%s", hashKeyEnv)
+	}
+	if o.formatPreserve {
+		cfg, err = enableFormatPreserving(cfg)
+		if err != nil {
+			return maskConfig{}, err
+		}
+	}
+	if o.dateShift {
+		cfg, err = enableDateShift(cfg, o.dateShiftMax)
+		if err != nil {
+			return maskConfig{}, err
+		}
+	}
+	if len(o.generalize) > 0 {
+		cfg, err = enableGeneralization(cfg, o.generalize, o.ageBucket, o.yearBucket)
+		if err != nil {
+			return maskConfig{}, err
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	decisionsName    = "redaction-decisions.json"
+	decisionsVersion = 1
+
+	decisionRedact = "redact"
+	decisionKeep   = "keep"
+)
+
+// decisionStore is the allow/deny list built up during interactive review.
+// Values are stored only as HMAC hashes under the hash key, so the file can
+// sit next to the outputs without leaking what was reviewed. An unkeyed hash
+// of a name or email could be confirmed by guessing, which is why a store
+// without a key refuses to hold or save decisions.
+type decisionStore struct {
+	path      string
+	key       string
+	keyID     string
+	decisions map[string]decision
+}
+
+type decision struct {
+	ValueHash string `json:"value_hash"`
+	Label     string `json:"label"`
+	Decision  string `json:"decision"`
+	DecidedAt string `json:"decided_at"`
+}
+
+type decisionsFile struct {
+	Version   int        `json:"version"`
+	KeyID     string     `json:"key_id,omitempty"`
+	Decisions []decision `json:"decisions"`
+}
+
+// loadDecisions reads a decisions file; a missing file yields an empty store.
+// Hashes are keyed with the hash key, so the file must be reused with the key
+// that wrote it.
+func loadDecisions(path string, key hashKey) (*decisionStore, error) {
+	store := &decisionStore{path: path, key: key.secret, keyID: key.id, decisions: map[string]decision{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file decisionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid decisions file %s: %w", path, err)
+	}
+	if len(file.Decisions) > 0 && key.secret == "" {
+		return nil, fmt.Errorf("decisions file %s needs the hash key it was written with; set -hash-key-file or $%s", path, hashKeyEnv)
+	}
+	if len(file.Decisions) > 0 && file.KeyID != key.id {
+		return nil, fmt.Errorf("decisions file %s was written with key %q but the current key is %q", path, file.KeyID, key.id)
+	}
+	for _, d := range file.Decisions {
+		store.decisions[d.ValueHash] = d
+	}
+	return store, nil
+}
+
+func (s *decisionStore) hash(value string) string {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	return hashMatch(normalized, "gs-decision\x00"+s.key, 64)
+}
+
+func (s *decisionStore) lookup(value string) string {
+	return s.decisions[s.hash(value)].Decision
+}
+
+func (s *decisionStore) set(label, value, verdict string) {
+	h := s.hash(value)
+	s.decisions[h] = decision{ValueHash: h, Label: label, Decision: verdict, DecidedAt: time.Now().Format(time.RFC3339)}
+}
+
+// digest identifies the current set of decisions for the incremental
+// fingerprint.
+func (s *decisionStore) digest() string {
+	if len(s.decisions) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(s.decisions))
+	for h, d := range s.decisions {
+		keys = append(keys, h+"="+d.Decision)
+	}
+	sort.Strings(keys)
+	return contentSHA256([]byte(strings.Join(keys, "\n")))
+}
+
+func (s *decisionStore) save() error {
+	if s.key == "" {
+		return errors.New("refusing to save decisions without a hash key")
+	}
+	file := decisionsFile{Version: decisionsVersion, KeyID: s.keyID, Decisions: make([]decision, 0, len(s.decisions))}
+	for _, d := range s.decisions {
+		file.Decisions = append(file.Decisions, d)
+	}
+	sort.Slice(file.Decisions, func(i, j int) bool {
+		return file.Decisions[i].ValueHash < file.Decisions[j].ValueHash
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, defaultOutputModes)
+}
+
+// reviewer applies stored decisions to each file's matches and, in
+// interactive mode, asks about the rest. Anything left undecided is redacted.
+type reviewer struct {
+	store       *decisionStore
+	interactive bool
+	in          *bufio.Reader
+	out         io.Writer
+	file        string
+	stopped     bool
+	err         error
+}
+
+func newReviewer(store *decisionStore, interactive bool, in io.Reader, out io.Writer) *reviewer {
+	return &reviewer{store: store, interactive: interactive, in: bufio.NewReader(in), out: out}
+}
+
+// review drops the matches the reviewer keeps. Prompts follow the order of
+// the text; the returned matches keep their original order.
+func (r *reviewer) review(content string, matches []match) []match {
+	order := make([]int, len(matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return matches[order[a]].start < matches[order[b]].start })
+
+	drop := make([]bool, len(matches))
+	for _, i := range order {
+		m := matches[i]
+		value := content[m.start:m.end]
+		switch r.store.lookup(value) {
+		case decisionKeep:
+			drop[i] = true
+			continue
+		case decisionRedact:
+			continue
+		}
+		if r.interactive && !r.stopped {
+			drop[i] = r.prompt(content, m) == decisionKeep
+		}
+	}
+
+	kept := matches[:0:0]
+	for i, m := range matches {
+		if !drop[i] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func (r *reviewer) prompt(content string, m match) string {
+	value := content[m.start:m.end]
+	line := strings.Count(content[:m.start], "\n") + 1
+	before, after := reviewContext(content, m.start, m.end, 40)
+	for {
+		fmt.Fprintf(r.out, "\n%s:%d  %s\n  %s[[%s]]%s\n", r.file, line, m.label, before, value, after)
+		fmt.Fprint(r.out, "[a]ccept  [r]eject  [A]ccept all for this value  [R]eject all for this value  [q]uit review: ")
+		answer, err := r.in.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if err != nil && answer == "" {
+			r.stopped = true
+			fmt.Fprintln(r.out)
+			return decisionRedact
+		}
+		switch answer {
+		case "a", "":
+			return decisionRedact
+		case "r":
+			return decisionKeep
+		case "A", "R":
+			verdict := decisionRedact
+			if answer == "R" {
+				verdict = decisionKeep
+			}
+			r.store.set(m.label, value, verdict)
+			if err := r.store.save(); err != nil && r.err == nil {
+				r.err = err
+			}
+			return verdict
+		case "q":
+			r.stopped = true
+			return decisionRedact
+		}
+		fmt.Fprintln(r.out, "Please answer a, r, A, R or q.")
+	}
+}
+
+// reviewContext returns up to width runes of same-line text on each side of
+// a match, with newlines shown as spaces.
+func reviewContext(content string, start, end, width int) (string, string) {
+	lineStart := strings.LastIndex(content[:start], "\n") + 1
+	lineEnd := len(content)
+	if i := strings.Index(content[end:], "\n"); i >= 0 {
+		lineEnd = end + i
+	}
+	before := content[lineStart:start]
+	if utf8.RuneCountInString(before) > width {
+		runes := []rune(before)
+		before = "…" + string(runes[len(runes)-width:])
+	}
+	after := content[end:lineEnd]
+	if utf8.RuneCountInString(after) > width {
+		after = string([]rune(after)[:width]) + "…"
+	}
+	return before, after
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReviewerPromptsAndRemembersValues(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "[{label}:{n}]", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), decisionsName)
+	store, err := loadDecisions(path, hashKey{secret: "k", id: "kid"})
+	if err != nil {
+		t.Fatalf("loadDecisions error: %v", err)
+	}
+	// Reject every occurrence of the first email, reject the phone once,
+	// accept the second email.
+	rev := newReviewer(store, true, strings.NewReader("R\nr\na\n"), io.Discard)
+	cfg.review = rev.review
+
+	content := "Mail help@school.org, help@school.org, call 555-123-4567, or jane@example.com."
+	redacted, _ := redactContent(content, patterns, cfg)
+	want := "Mail help@school.org, help@school.org, call 555-123-4567, or [email:1]."
+	if redacted != want {
+		t.Fatalf("expected %q, got %q", want, redacted)
+	}
+
+	reloaded, err := loadDecisions(path, hashKey{secret: "k", id: "kid"})
+	if err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if reloaded.lookup("HELP@school.org") != decisionKeep || reloaded.lookup("555-123-4567") != "" {
+		t.Fatalf("expected only the value-wide decision to be saved, got %+v", reloaded.decisions)
+	}
+	if _, err := loadDecisions(path, hashKey{secret: "other", id: "other"}); err == nil {
+		t.Fatalf("expected error when the decisions file was written with another key")
+	}
+}
+
+func TestReviewerAppliesStoredDecisionsWithoutPrompting(t *testing.T) {
+	store, err := loadDecisions(filepath.Join(t.TempDir(), decisionsName), hashKey{secret: "k", id: "kid"})
+	if err != nil {
+		t.Fatalf("loadDecisions error: %v", err)
+	}
+	store.set("email", "help@school.org", decisionKeep)
+	rev := newReviewer(store, false, strings.NewReader(""), io.Discard)
+
+	content := "help@school.org and jane@example.com"
+	matches := []match{{label: "email", start: 0, end: 15}, {label: "email", start: 20, end: 36}}
+	kept := rev.review(content, matches)
+	if len(kept) != 1 || kept[0].start != 20 {
+		t.Fatalf("expected only the undecided match to remain, got %+v", kept)
+	}
+	if strings.Contains(store.hash("help@school.org"), "help") {
+		t.Fatalf("expected hashed values only")
+	}
+}
+
+func TestDecisionsRequireHashKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), decisionsName)
+	store, err := loadDecisions(path, hashKey{})
+	if err != nil {
+		t.Fatalf("loadDecisions error: %v", err)
+	}
+	store.set("email", "help@school.org", decisionKeep)
+	if err := store.save(); err == nil {
+		t.Fatalf("expected saving without a hash key to fail")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no decisions file, got %v", err)
+	}
+
+	keyed, err := loadDecisions(path, hashKey{secret: "k", id: "kid"})
+	if err != nil {
+		t.Fatalf("loadDecisions error: %v", err)
+	}
+	keyed.set("email", "help@school.org", decisionKeep)
+	if err := keyed.save(); err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+	if _, err := loadDecisions(path, hashKey{}); err == nil {
+		t.Fatalf("expected stored decisions to need the hash key")
+	}
+}
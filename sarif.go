@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName = "groupscholar-essay-anonymizer"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	ColumnKind         string                      `json:"columnKind"`
+	OriginalURIBaseIDs map[string]sarifArtifactURI `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactURI `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactURI struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	ByteOffset  int `json:"byteOffset"`
+	ByteLength  int `json:"byteLength"`
+}
+
+// buildSARIF turns the report into a SARIF log with one rule per pattern
+// label and one result per finding. Artifact URIs are relative to the input
+// directory (or the input file's directory) under the INPUT base ID.
+func buildSARIF(rep report, labels []string) sarifLog {
+	seen := map[string]bool{}
+	var ruleLabels []string
+	addLabel := func(label string) {
+		if !seen[label] {
+			seen[label] = true
+			ruleLabels = append(ruleLabels, label)
+		}
+	}
+	for _, label := range labels {
+		addLabel(label)
+	}
+	for _, entry := range rep.Details {
+		for _, f := range entry.Findings {
+			addLabel(f.Label)
+		}
+	}
+	sort.Strings(ruleLabels)
+
+	ruleIndex := map[string]int{}
+	rules := make([]sarifRule, 0, len(ruleLabels))
+	for i, label := range ruleLabels {
+		ruleIndex[label] = i
+		rules = append(rules, sarifRule{
+			ID:                   label,
+			Name:                 sarifRuleName(label),
+			ShortDescription:     sarifMessage{Text: "Possible PII: " + label},
+			DefaultConfiguration: sarifConfiguration{Level: "warning"},
+		})
+	}
+
+	baseDir := rep.InputPath
+	if info, err := os.Stat(baseDir); err == nil && !info.IsDir() {
+		baseDir = filepath.Dir(baseDir)
+	}
+	run := sarifRun{
+		Tool:       sarifTool{Driver: sarifDriver{Name: sarifToolName, Rules: rules}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	if baseDir != "" {
+		base := url.URL{Scheme: "file", Path: strings.TrimSuffix(filepath.ToSlash(baseDir), "/") + "/"}
+		run.OriginalURIBaseIDs = map[string]sarifArtifactURI{"INPUT": {URI: base.String()}}
+	}
+
+	for _, entry := range rep.Details {
+		artifact := sarifArtifactURI{URI: filepath.ToSlash(entry.Source)}
+		if rel, err := filepath.Rel(baseDir, entry.Source); err == nil && baseDir != "" {
+			artifact = sarifArtifactURI{URI: (&url.URL{Path: filepath.ToSlash(rel)}).String(), URIBaseID: "INPUT"}
+		}
+		for _, f := range entry.Findings {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    f.Label,
+				RuleIndex: ruleIndex[f.Label],
+				Level:     "warning",
+				Message:   sarifMessage{Text: fmt.Sprintf("Possible %s redacted at line %d.", f.Label, f.Line)},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: artifact,
+					Region: sarifRegion{
+						StartLine:   f.Line,
+						StartColumn: f.Column,
+						EndLine:     f.EndLine,
+						EndColumn:   f.EndColumn,
+						ByteOffset:  f.Offset,
+						ByteLength:  f.Length,
+					},
+				}}},
+			})
+		}
+	}
+	return sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// sarifRuleName turns a label such as "social:instagram" into a PascalCase
+// rule name ("SocialInstagram") as SARIF viewers expect.
+func sarifRuleName(label string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(label, func(r rune) bool {
+		return r == ':' || r == '_' || r == '-' || r == ' '
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func writeSARIFReport(path string, rep report, labels []string, modes outputModes) error {
+	data, err := json.MarshalIndent(buildSARIF(rep, labels), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, modes)
+}
+
+func patternLabels(patterns []pattern) []string {
+	labels := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		labels = append(labels, p.label)
+	}
+	return labels
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocateFindingsLinesAndColumns(t *testing.T) {
+	content := "Café note\nEmail jane@example.com or call 555-123-4567"
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	findings := locateFindings(content, findMatches(content, patterns, maskConfig{}), "")
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	email := findings[0]
+	if email.Label != "email" || email.Line != 2 || email.Column != 7 || email.EndColumn != 23 {
+		t.Fatalf("unexpected email finding: %+v", email)
+	}
+	if content[email.Offset:email.Offset+email.Length] != "jane@example.com" {
+		t.Fatalf("unexpected email offsets: %+v", email)
+	}
+	if findings[1].Label != "phone" || findings[1].Line != 2 {
+		t.Fatalf("unexpected phone finding: %+v", findings[1])
+	}
+}
+
+func TestWriteSARIFReportOmitsMatchedText(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "essays")
+	mustMkdir(t, filepath.Join(input, "fall"))
+	rep := report{
+		InputPath: input,
+		ByPattern: map[string]int{"email": 1},
+		Details: []fileReport{{
+			Source:   filepath.Join(input, "fall", "essay one.txt"),
+			Total:    1,
+			Findings: []finding{{Label: "email", Offset: 6, Length: 16, Line: 1, Column: 7, EndLine: 1, EndColumn: 23}},
+		}},
+	}
+	path := filepath.Join(root, "out", "findings.sarif")
+	if err := writeSARIFReport(path, rep, []string{"phone", "email", "social:instagram"}, defaultOutputModes); err != nil {
+		t.Fatalf("writeSARIFReport error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || len(run.Tool.Driver.Rules) != 3 || len(run.Results) != 1 {
+		t.Fatalf("unexpected SARIF shape: %s", data)
+	}
+	result := run.Results[0]
+	if result.RuleID != "email" || run.Tool.Driver.Rules[result.RuleIndex].ID != "email" {
+		t.Fatalf("unexpected rule reference: %+v", result)
+	}
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "fall/essay%20one.txt" || loc.ArtifactLocation.URIBaseID != "INPUT" || loc.Region.StartColumn != 7 {
+		t.Fatalf("unexpected location: %+v", loc)
+	}
+	if run.Tool.Driver.Rules[2].Name != "SocialInstagram" {
+		t.Fatalf("unexpected rule name: %q", run.Tool.Driver.Rules[2].Name)
+	}
+	if strings.Contains(string(data), "@") {
+		t.Fatalf("SARIF output must not contain matched text: %s", data)
+	}
+}
+
+func TestFindingsOmitUnkeyedValueHash(t *testing.T) {
+	content := "SSN 123-45-6789"
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	matches := findMatches(content, patterns, maskConfig{})
+	data, err := json.Marshal(manifestEntry{Findings: locateFindings(content, matches, "")})
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if strings.Contains(string(data), "value_hash") {
+		t.Fatalf("expected no value_hash without a hash key, got %s", data)
+	}
+	if keyed := locateFindings(content, matches, "key"); keyed[0].ValueHash != hashMatch("123-45-6789", "key", 64) {
+		t.Fatalf("expected keyed value hash, got %+v", keyed[0])
+	}
+}
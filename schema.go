@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//go:embed data/report.schema.json
+var reportSchemaJSON []byte
+
+var reportSchema = mustParseSchema(reportSchemaJSON)
+
+func mustParseSchema(data []byte) map[string]any {
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		panic("invalid embedded report schema: " + err.Error())
+	}
+	return schema
+}
+
+// validateReportJSON checks an encoded report against the embedded schema.
+func validateReportJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	return validateSchema(reportSchema, reportSchema, value, "$")
+}
+
+// validateSchema implements the subset of JSON Schema the report schema
+// uses: type, const, enum, minimum, required, properties,
+// additionalProperties, items and local $ref.
+func validateSchema(root, schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := resolveSchemaRef(root, ref)
+		if err != nil {
+			return err
+		}
+		return validateSchema(root, target, value, path)
+	}
+	if types, ok := schema["type"]; ok && !schemaTypeMatches(types, value) {
+		return fmt.Errorf("%s: expected %v, got %s", path, types, jsonTypeName(value))
+	}
+	if want, ok := schema["const"]; ok && !jsonEqual(want, value) {
+		return fmt.Errorf("%s: expected %v", path, want)
+	}
+	if options, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, option := range options {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in %v", path, options)
+		}
+	}
+	if minimum, ok := schema["minimum"].(float64); ok {
+		if n, isNumber := value.(json.Number); isNumber {
+			if f, err := n.Float64(); err == nil && f < minimum {
+				return fmt.Errorf("%s: %s is below the minimum %v", path, n, minimum)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, present := v[name.(string)]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "." + key
+			if sub, ok := properties[key].(map[string]any); ok {
+				if err := validateSchema(root, sub, v[key], child); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s: unexpected property", child)
+				}
+			case map[string]any:
+				if err := validateSchema(root, extra, v[key], child); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func resolveSchemaRef(root map[string]any, ref string) (map[string]any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported schema reference %q", ref)
+	}
+	var node any = root
+	for _, part := range strings.Split(pointer, "/") {
+		object, isObject := node.(map[string]any)
+		if !isObject {
+			return nil, fmt.Errorf("unresolvable schema reference %q", ref)
+		}
+		node = object[part]
+	}
+	target, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable schema reference %q", ref)
+	}
+	return target, nil
+}
+
+func schemaTypeMatches(types any, value any) bool {
+	switch t := types.(type) {
+	case string:
+		return jsonTypeMatches(t, value)
+	case []any:
+		for _, option := range t {
+			if name, ok := option.(string); ok && jsonTypeMatches(name, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeMatches(name string, value any) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	}
+	return jsonTypeName(value) == name
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares a schema literal (decoded with float64 numbers) to a
+// value decoded with json.Number.
+func jsonEqual(want, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return err == nil && reflect.DeepEqual(want, f)
+	}
+	return reflect.DeepEqual(want, value)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateReportJSONAcceptsWrittenReports(t *testing.T) {
+	rep := report{
+		SchemaVersion: reportSchemaVersion,
+		Status:        statusComplete,
+		ByPattern:     map[string]int{},
+		Errors:        []fileError{{Path: "b.txt", Stage: "decode", Message: "file is not valid UTF-8"}},
+		Violations:    []thresholdViolation{{Rule: "ssn>=1", Count: 1}},
+	}
+	rep.add(fileReport{Source: "a.txt", Redactions: map[string]int{"ssn": 1}, Total: 1, Findings: []finding{{Label: "ssn", Line: 1, Column: 1}}})
+	rep.attachMatchDetails()
+	data, err := json.Marshal(rep)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if err := validateReportJSON(data); err != nil {
+		t.Fatalf("expected report to validate, got %v", err)
+	}
+}
+
+func TestValidateReportJSONRejectsInvalidReports(t *testing.T) {
+	valid := `{"schema_version":"2","generated_at":"","input_path":"","output_path":"","status":"complete","files":0,"total_redactions":0,"by_pattern":{},"details":[]}`
+	cases := map[string]string{
+		`"schema_version":"2"`:  `"schema_version":"3"`,
+		`"status":"complete"`:   `"status":"done"`,
+		`"files":0`:             `"files":-1`,
+		`"by_pattern":{}`:       `"by_pattern":{"email":1.5}`,
+		`"details":[]`:          `"details":[{"source":"a"}]`,
+		`"total_redactions":0,`: `"total_redactions":0,"extra":true,`,
+	}
+	if err := validateReportJSON([]byte(valid)); err != nil {
+		t.Fatalf("expected minimal report to validate, got %v", err)
+	}
+	for from, to := range cases {
+		broken := strings.Replace(valid, from, to, 1)
+		if err := validateReportJSON([]byte(broken)); err == nil {
+			t.Fatalf("expected %s to fail validation", to)
+		}
+	}
+}
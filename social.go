@@ -0,0 +1,63 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+type socialPlatform struct {
+	key      string
+	name     string
+	keywords string
+	profile  string
+}
+
+// socialPlatforms lists the profile URL shapes (with or without a scheme)
+// and the words that mark a nearby @handle as belonging to the platform.
+var socialPlatforms = []socialPlatform{
+	{key: "instagram", name: "Instagram", keywords: `instagram|insta|ig`, profile: `(?:instagram\.com|instagr\.am)/[A-Za-z0-9_.]+`},
+	{key: "tiktok", name: "TikTok", keywords: `tiktok|tik tok`, profile: `tiktok\.com/@[A-Za-z0-9_.]+`},
+	{key: "linkedin", name: "LinkedIn", keywords: `linkedin`, profile: `linkedin\.com/(?:in|company|pub)/[A-Za-z0-9_\-%]+`},
+	{key: "github", name: "GitHub", keywords: `github`, profile: `github\.com/[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?(?:/[A-Za-z0-9_.\-]+)?`},
+	{key: "youtube", name: "YouTube", keywords: `youtube|yt`, profile: `(?:youtube\.com/(?:@|c/|channel/|user/)[A-Za-z0-9_.\-]+|youtu\.be/[A-Za-z0-9_\-]+)`},
+	{key: "twitter", name: "X (Twitter)", keywords: `twitter|tweet`, profile: `(?:twitter\.com|x\.com)/[A-Za-z0-9_]+`},
+	{key: "facebook", name: "Facebook", keywords: `facebook|fb`, profile: `(?:facebook\.com|fb\.com)/[A-Za-z0-9_.\-]+`},
+}
+
+// profileRest carries a profile match on through any further path, query or
+// fragment, stopping short of trailing sentence punctuation.
+const profileRest = `(?:[/?#](?:[^\s]*[^\s.,;:!?)\]}'"])?)?`
+
+const socialHandle = `(@[A-Za-z0-9_](?:[A-Za-z0-9_.]{0,28}[A-Za-z0-9_])?)`
+
+// buildSocialPatterns returns, per platform, a profile URL pattern and
+// handle-in-context patterns ("my Instagram is @jane", "@jane on TikTok"),
+// followed by a catch-all for bare @handles.
+func buildSocialPatterns() []pattern {
+	var patterns []pattern
+	for _, platform := range socialPlatforms {
+		label := "social:" + platform.key
+		patterns = append(patterns,
+			pattern{label: label, re: regexp.MustCompile(`(?i)(?:\bhttps?://)?(?:\b(?:www|m)\.)?\b` + platform.profile + profileRest)},
+			pattern{label: label, re: regexp.MustCompile(`(?i)\b(?:` + platform.keywords + `)\b[^\n@,;.!?]{0,20}?` + socialHandle), group: 1},
+			pattern{label: label, re: regexp.MustCompile(`(?i)` + socialHandle + `\s+(?:on|via|at)\s+(?:` + platform.keywords + `)\b`), group: 1},
+		)
+	}
+	patterns = append(patterns, pattern{label: "social:handle", re: regexp.MustCompile(`(?:^|[\s(\[{"'])` + socialHandle + `\b`), group: 1})
+	return patterns
+}
+
+// platformName returns the display name used by the {platform} placeholder.
+// Labels that are not social fall back to the label itself.
+func platformName(label string) string {
+	key, ok := strings.CutPrefix(label, "social:")
+	if !ok {
+		return label
+	}
+	for _, platform := range socialPlatforms {
+		if platform.key == key {
+			return platform.name
+		}
+	}
+	return "social media"
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRedactContentSocialProfilesAndHandles(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	cfg, err := buildMaskConfig("[REDACTED]", "(link to portfolio on {platform})", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+
+	content := "See github.com/janedoe and https://www.linkedin.com/in/jane-doe-123/. " +
+		"Watch youtube.com/@janedoe/videos?view=0&sort=p#top, then instagram.com/jane.doe/reels/C1x2y3/! " +
+		"My Instagram is @jane.doe, I post as @janedoe on TikTok, ping @jdoe_07 or email jane@example.com."
+	redacted, counts := redactContent(content, patterns, cfg)
+	want := "See (link to portfolio on GitHub) and (link to portfolio on LinkedIn). " +
+		"Watch (link to portfolio on YouTube), then (link to portfolio on Instagram)! " +
+		"My Instagram is (link to portfolio on Instagram), I post as (link to portfolio on TikTok) on TikTok, " +
+		"ping (link to portfolio on social media) or email (link to portfolio on email)."
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+	expected := map[string]int{
+		"social:github":    1,
+		"social:linkedin":  1,
+		"social:instagram": 2,
+		"social:youtube":   1,
+		"social:tiktok":    1,
+		"social:handle":    1,
+		"email":            1,
+	}
+	for label, count := range expected {
+		if counts[label] != count {
+			t.Fatalf("expected %d %s, got %#v", count, label, counts)
+		}
+	}
+}
+
+func TestPlatformName(t *testing.T) {
+	if platformName("social:youtube") != "YouTube" || platformName("social:handle") != "social media" || platformName("email") != "email" {
+		t.Fatalf("unexpected platform names")
+	}
+}
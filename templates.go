@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maskPlaceholders lists every placeholder a mask template may use.
+var maskPlaceholders = map[string]bool{
+	"label":    true,
+	"n":        true,
+	"hash":     true,
+	"keyid":    true,
+	"platform": true,
+	"last4":    true,
+	"len":      true,
+	"initials": true,
+}
+
+var placeholderRe = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+type labelTemplate struct {
+	label    string
+	prefix   bool
+	template string
+}
+
+// validateMaskTemplate rejects unknown placeholders so a typo such as
+// {lats4} fails at startup instead of leaking into every output file.
+func validateMaskTemplate(template string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if !maskPlaceholders[m[1]] {
+			return fmt.Errorf("unknown placeholder {%s} in mask template %q", m[1], template)
+		}
+	}
+	return nil
+}
+
+// parseLabelTemplates parses -label-template values of the form
+// label=template, where label may end in '*' to match a prefix.
+func parseLabelTemplates(values []string, hashEnabled bool) ([]labelTemplate, error) {
+	var templates []labelTemplate
+	for _, raw := range values {
+		label, template, ok := strings.Cut(raw, "=")
+		label = strings.TrimSpace(label)
+		template = strings.TrimSpace(template)
+		if !ok || label == "" || template == "" {
+			return nil, fmt.Errorf("invalid label template %q (expected label=template)", raw)
+		}
+		if err := validateMaskTemplate(template); err != nil {
+			return nil, err
+		}
+		if hashEnabled && !strings.Contains(template, "{hash}") {
+			return nil, fmt.Errorf("label template for %s must include {hash} when --hash is enabled", label)
+		}
+		entry := labelTemplate{label: label, template: template}
+		if strings.HasSuffix(label, "*") {
+			entry.label = strings.TrimSuffix(label, "*")
+			entry.prefix = true
+		}
+		templates = append(templates, entry)
+	}
+	return templates, nil
+}
+
+func applyLabelTemplates(cfg maskConfig, values []string) (maskConfig, error) {
+	templates, err := parseLabelTemplates(values, cfg.useHash)
+	if err != nil {
+		return maskConfig{}, err
+	}
+	cfg.labelTemplates = templates
+	return cfg, nil
+}
+
+// templateFor picks the template for a label: an exact label override, then
+// the longest matching prefix override, then the global template.
+func (cfg maskConfig) templateFor(label string) string {
+	if entry, ok := cfg.labelTemplateFor(label); ok {
+		return entry.template
+	}
+	return strings.TrimSpace(cfg.template)
+}
+
+func (cfg maskConfig) labelTemplateFor(label string) (labelTemplate, bool) {
+	var best labelTemplate
+	found := false
+	for _, entry := range cfg.labelTemplates {
+		switch {
+		case !entry.prefix && entry.label == label:
+			return entry, true
+		case entry.prefix && strings.HasPrefix(label, entry.label) && (!found || len(entry.label) > len(best.label)):
+			best, found = entry, true
+		}
+	}
+	return best, found
+}
+
+// numberingGroup names the labels that share {n} numbering: every label
+// under a prefix template such as name:*, otherwise the label itself.
+func (cfg maskConfig) numberingGroup(label string) string {
+	if entry, ok := cfg.labelTemplateFor(label); ok && entry.prefix {
+		return entry.label + "*"
+	}
+	return label
+}
+
+// usesHashTokens reports whether any replacement can render a {hash} token.
+func (cfg maskConfig) usesHashTokens() bool {
+	if cfg.useHash || strings.Contains(cfg.template, "{hash}") {
+		return true
+	}
+	for _, entry := range cfg.labelTemplates {
+		if strings.Contains(entry.template, "{hash}") {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg maskConfig) hasLabelTemplate(label string) bool {
+	for _, entry := range cfg.labelTemplates {
+		if entry.label == label || (entry.prefix && strings.HasPrefix(label, entry.label)) {
+			return true
+		}
+	}
+	return false
+}
+
+func renderMaskTemplate(template, label, match string, index int, hash, keyID string) string {
+	out := applyMaskTemplate(template, label, index, hash)
+	if !strings.Contains(out, "{") {
+		return out
+	}
+	out = strings.ReplaceAll(out, "{keyid}", keyID)
+	out = strings.ReplaceAll(out, "{platform}", platformName(label))
+	out = strings.ReplaceAll(out, "{last4}", lastAlphanumeric(match, 4))
+	out = strings.ReplaceAll(out, "{len}", strconv.Itoa(len([]rune(match))))
+	return strings.ReplaceAll(out, "{initials}", initials(match))
+}
+
+func lastAlphanumeric(value string, count int) string {
+	var kept []rune
+	runes := []rune(value)
+	for i := len(runes) - 1; i >= 0 && len(kept) < count; i-- {
+		if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+			kept = append([]rune{runes[i]}, kept...)
+		}
+	}
+	return string(kept)
+}
+
+func initials(value string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '.'
+	}) {
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				b.WriteRune(unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelTemplatesOverrideGlobalTemplate(t *testing.T) {
+	patterns, err := buildPatterns(nil)
+	if err != nil {
+		t.Fatalf("buildPatterns error: %v", err)
+	}
+	patterns = append(patterns, buildNamePatterns([]string{"Jordan Lee", "Sam Rivera"})...)
+	cfg, err := buildMaskConfig("[REDACTED]", "[{label}]", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = applyLabelTemplates(cfg, []string{
+		"email=[EMAIL]",
+		"ssn=***-**-{last4}",
+		"name:*=[NAME_{initials}_{len}]",
+	})
+	if err != nil {
+		t.Fatalf("label template error: %v", err)
+	}
+
+	content := "Jordan Lee (jordan@example.com, SSN 123-45-6789) thanked Sam Rivera. Visit https://example.org"
+	redacted, _ := redactContent(content, patterns, cfg)
+	want := "[NAME_JL_10] ([EMAIL], SSN ***-**-6789) thanked [NAME_SR_10]. Visit [url]"
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+}
+
+func TestLabelTemplatesPreferExactOverPrefix(t *testing.T) {
+	cfg, err := applyLabelTemplates(maskConfig{mask: "[REDACTED]"}, []string{"name:*=[NAME]", "name:Jordan=[J]", "n*=[N]"})
+	if err != nil {
+		t.Fatalf("label template error: %v", err)
+	}
+	if got := cfg.templateFor("name:Jordan"); got != "[J]" {
+		t.Fatalf("expected exact override, got %q", got)
+	}
+	if got := cfg.templateFor("name:Sam"); got != "[NAME]" {
+		t.Fatalf("expected longest prefix override, got %q", got)
+	}
+	if got := cfg.templateFor("email"); got != "" {
+		t.Fatalf("expected no template for email, got %q", got)
+	}
+}
+
+func TestLabelTemplateValidation(t *testing.T) {
+	if _, err := applyLabelTemplates(maskConfig{}, []string{"ssn=***-**-{lats4}"}); err == nil || !strings.Contains(err.Error(), "unknown placeholder") {
+		t.Fatalf("expected unknown placeholder error, got %v", err)
+	}
+	if _, err := applyLabelTemplates(maskConfig{}, []string{"ssn"}); err == nil {
+		t.Fatalf("expected error for missing template")
+	}
+	if _, err := applyLabelTemplates(maskConfig{useHash: true}, []string{"email=[EMAIL]"}); err == nil {
+		t.Fatalf("expected error when hash is enabled without {hash}")
+	}
+	if _, err := buildMaskConfig("[REDACTED]", "[{labl}]", false, "", 8); err == nil {
+		t.Fatalf("expected unknown placeholder error for global template")
+	}
+}
+
+func TestPrefixTemplateNumbersDistinctValues(t *testing.T) {
+	patterns := buildNamePatterns([]string{"Jordan", "Smith"})
+	cfg, err := buildMaskConfig("[REDACTED]", "", false, "", 8)
+	if err != nil {
+		t.Fatalf("mask config error: %v", err)
+	}
+	cfg, err = applyLabelTemplates(cfg, []string{"name:*=[NAME_{n}]"})
+	if err != nil {
+		t.Fatalf("label template error: %v", err)
+	}
+
+	redacted, _ := redactContent("Jordan met Smith. Later Jordan called.", patterns, cfg)
+	want := "[NAME_1] met [NAME_2]. Later [NAME_1] called."
+	if redacted != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", redacted, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// failOnRule is a parsed -fail-on rule such as "ssn>=1", "id:*>0" or
+// "file:total>50". Run rules compare run-wide counts; file rules compare
+// each file's counts.
+type failOnRule struct {
+	raw       string
+	perFile   bool
+	total     bool
+	labels    labelMatcher
+	inclusive bool
+	limit     int
+}
+
+// thresholdViolation records a -fail-on rule that tripped.
+type thresholdViolation struct {
+	Rule  string `json:"rule"`
+	Path  string `json:"path,omitempty"`
+	Count int    `json:"count"`
+}
+
+func parseFailOnRules(values []string) ([]failOnRule, error) {
+	var rules []failOnRule
+	for _, raw := range values {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			rule, err := parseFailOnRule(part)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func parseFailOnRule(raw string) (failOnRule, error) {
+	rule := failOnRule{raw: raw}
+	subject, limit, ok := strings.Cut(raw, ">=")
+	if ok {
+		rule.inclusive = true
+	} else if subject, limit, ok = strings.Cut(raw, ">"); !ok {
+		return failOnRule{}, fmt.Errorf("invalid -fail-on rule %q (expected [file:]label>=N or [file:]label>N)", raw)
+	}
+	subject = strings.TrimSpace(subject)
+	if rest, found := strings.CutPrefix(subject, "file:"); found {
+		rule.perFile = true
+		subject = rest
+	}
+	if subject == "" {
+		return failOnRule{}, fmt.Errorf("invalid -fail-on rule %q: missing label", raw)
+	}
+	if subject == "total" {
+		rule.total = true
+	} else {
+		rule.labels = buildLabelMatcher([]string{subject})
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n < 0 {
+		return failOnRule{}, fmt.Errorf("invalid -fail-on rule %q: threshold must be a non-negative integer", raw)
+	}
+	rule.limit = n
+	return rule, nil
+}
+
+func (r failOnRule) count(total int, byLabel map[string]int) int {
+	if r.total {
+		return total
+	}
+	sum := 0
+	for label, count := range byLabel {
+		if r.labels.matches(label) {
+			sum += count
+		}
+	}
+	return sum
+}
+
+func (r failOnRule) exceeded(count int) bool {
+	if r.inclusive {
+		return count >= r.limit
+	}
+	return count > r.limit
+}
+
+// evaluateFailOn returns every rule violation in the report, run rules first
+// and then file rules in report order.
+func evaluateFailOn(rules []failOnRule, rep report) []thresholdViolation {
+	var violations []thresholdViolation
+	for _, rule := range rules {
+		if rule.perFile {
+			continue
+		}
+		if count := rule.count(rep.Total, rep.ByPattern); rule.exceeded(count) {
+			violations = append(violations, thresholdViolation{Rule: rule.raw, Count: count})
+		}
+	}
+	for _, rule := range rules {
+		if !rule.perFile {
+			continue
+		}
+		for _, entry := range rep.Details {
+			if count := rule.count(entry.Total, entry.Redactions); rule.exceeded(count) {
+				violations = append(violations, thresholdViolation{Rule: rule.raw, Path: entry.Source, Count: count})
+			}
+		}
+	}
+	return violations
+}
+
+// runExitCode picks the exit code for a finished run. File errors outrank
+// threshold violations; with detailed codes a run that made redactions is
+// distinguished from a clean one.
+func runExitCode(rep report, detailed bool) int {
+	switch {
+	case len(rep.Errors) > 0:
+		return exitFileErrors
+	case len(rep.Violations) > 0:
+		return exitThreshold
+	case detailed && rep.Total > 0:
+		return exitRedacted
+	default:
+		return exitClean
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestParseFailOnRules(t *testing.T) {
+	rules, err := parseFailOnRules([]string{"ssn>=1", "file:total>50,id:*>0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+	if !rules[0].inclusive || rules[0].limit != 1 || rules[0].perFile {
+		t.Fatalf("unexpected ssn rule: %+v", rules[0])
+	}
+	if !rules[1].perFile || !rules[1].total || rules[1].inclusive || rules[1].limit != 50 {
+		t.Fatalf("unexpected file total rule: %+v", rules[1])
+	}
+	if !rules[2].labels.matches("id:ein") {
+		t.Fatalf("expected prefix rule to match id:ein")
+	}
+	for _, raw := range []string{"ssn", "ssn>=x", ">=1", "file:>2", "ssn>-1"} {
+		if _, err := parseFailOnRules([]string{raw}); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestEvaluateFailOn(t *testing.T) {
+	rep := report{
+		Total:     53,
+		ByPattern: map[string]int{"ssn": 1, "email": 52},
+		Details: []fileReport{
+			{Source: "essay.txt", Total: 3, Redactions: map[string]int{"ssn": 1, "email": 2}},
+			{Source: "form.txt", Total: 50, Redactions: map[string]int{"email": 50}},
+		},
+	}
+	rules, err := parseFailOnRules([]string{"ssn>=1", "file:total>50", "file:email>=50", "phone>=1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := evaluateFailOn(rules, rep)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 violations, got %+v", got)
+	}
+	if got[0].Rule != "ssn>=1" || got[0].Path != "" || got[0].Count != 1 {
+		t.Fatalf("unexpected run violation: %+v", got[0])
+	}
+	if got[1].Rule != "file:email>=50" || got[1].Path != "form.txt" || got[1].Count != 50 {
+		t.Fatalf("unexpected file violation: %+v", got[1])
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	cases := []struct {
+		rep      report
+		detailed bool
+		want     int
+	}{
+		{report{}, true, exitClean},
+		{report{Total: 2}, false, exitClean},
+		{report{Total: 2}, true, exitRedacted},
+		{report{Total: 2, Violations: []thresholdViolation{{Rule: "ssn>=1"}}}, true, exitThreshold},
+		{report{Total: 2, Errors: []fileError{{Path: "a"}}, Violations: []thresholdViolation{{Rule: "ssn>=1"}}}, false, exitFileErrors},
+	}
+	for i, tc := range cases {
+		if got := runExitCode(tc.rep, tc.detailed); got != tc.want {
+			t.Fatalf("case %d: expected exit %d, got %d", i, tc.want, got)
+		}
+	}
+}